@@ -1,10 +1,18 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flyola-services/internal/config"
 	"flyola-services/internal/database"
+	"flyola-services/internal/jobs"
 	"flyola-services/internal/router"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -25,12 +33,31 @@ func main() {
 	}
 	log.Println("✅ Database connected successfully")
 
+	// Stop background jobs and the server on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Start background jobs
+	jobs.Start(ctx, db, cfg)
+
 	// Initialize router with dependencies
 	r := router.Initialize(db, cfg)
 
 	// Start server
-	log.Printf("🏨 Flyola Hotel Services Backend starting on port %s\n", cfg.Port)
-	if err := r.Run(":" + cfg.Port); err != nil {
-		log.Fatal("Failed to start server:", err)
+	srv := &http.Server{Addr: ":" + cfg.Port, Handler: r}
+	go func() {
+		log.Printf("🏨 Flyola Hotel Services Backend starting on port %s\n", cfg.Port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Failed to start server:", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("🛑 Shutting down server...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Fatal("Server forced to shutdown:", err)
 	}
 }
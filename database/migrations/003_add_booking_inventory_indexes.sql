@@ -0,0 +1,9 @@
+-- Migration: Indexes for room inventory checks
+-- Date: 2026-10-17
+-- Description: Booking creation now checks overlapping stays and locks room_availability rows per room and date range
+
+ALTER TABLE `hotel_bookings`
+ADD KEY `idx_room_stay` (`room_id`, `check_in_date`, `check_out_date`);
+
+ALTER TABLE `room_availability`
+ADD KEY `idx_room_date` (`room_id`, `date`);
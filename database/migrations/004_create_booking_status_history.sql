@@ -0,0 +1,16 @@
+-- Migration: Hotel booking status history
+-- Date: 2026-10-17
+-- Description: Records every hotel booking lifecycle transition with actor, reason and timestamp
+
+CREATE TABLE IF NOT EXISTS `booking_status_history` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+    `booking_id` bigint unsigned NOT NULL,
+    `from_status` varchar(20) DEFAULT NULL,
+    `to_status` varchar(20) NOT NULL,
+    `payment_status` varchar(20) DEFAULT NULL,
+    `actor` varchar(100) NOT NULL,
+    `reason` text,
+    `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    KEY `idx_booking_status_history_booking_id` (`booking_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,14 @@
+-- Migration: Expiry of unpaid package bookings
+-- Date: 2026-10-17
+-- Description: Unpaid package bookings are expired by a background job; the reason is kept on the booking
+
+ALTER TABLE `package_bookings`
+MODIFY COLUMN `booking_status` enum('pending','confirmed','cancelled','completed','expired') NOT NULL DEFAULT 'pending',
+ADD COLUMN `status_reason` text AFTER `booking_status`;
+
+-- The expiry job looks for old unpaid bookings in both tables
+ALTER TABLE `package_bookings`
+ADD KEY `idx_pending_created` (`booking_status`, `payment_status`, `created_at`);
+
+ALTER TABLE `hotel_bookings`
+ADD KEY `idx_pending_created` (`booking_status`, `payment_status`, `created_at`);
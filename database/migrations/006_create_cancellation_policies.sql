@@ -0,0 +1,22 @@
+-- Migration: Cancellation policies
+-- Date: 2026-10-17
+-- Description: Hotel and package level cancellation rules, snapshotted on every booking, and package refunds
+
+CREATE TABLE IF NOT EXISTS `cancellation_policies` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+    `hotel_id` bigint unsigned DEFAULT NULL,
+    `package_id` bigint unsigned DEFAULT NULL,
+    `rules` json NOT NULL COMMENT 'Array of {hours_before, refund_percent}',
+    `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_cancellation_policies_hotel_id` (`hotel_id`),
+    UNIQUE KEY `idx_cancellation_policies_package_id` (`package_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE `hotel_bookings`
+ADD COLUMN `cancellation_rules` json DEFAULT NULL AFTER `payment_method`;
+
+ALTER TABLE `package_bookings`
+ADD COLUMN `cancellation_rules` json DEFAULT NULL COMMENT 'Cancellation rules of the package at the time of booking' AFTER `special_requests`,
+ADD COLUMN `refund_amount` decimal(10,2) DEFAULT 0 AFTER `cancellation_rules`;
@@ -0,0 +1,21 @@
+-- Migration: Meal plan rates
+-- Date: 2026-10-17
+-- Description: Per room category meal plan prices and the meal plan chosen on a hotel booking
+
+CREATE TABLE IF NOT EXISTS `meal_plan_rates` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+    `room_category_id` bigint unsigned NOT NULL,
+    `meal_plan_id` bigint unsigned NOT NULL,
+    `price_per_person` decimal(10,2) NOT NULL COMMENT 'Per person per night',
+    `valid_from` date DEFAULT NULL,
+    `valid_to` date DEFAULT NULL,
+    `status` bigint DEFAULT 0 COMMENT '0: Active, 1: Inactive',
+    `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    KEY `idx_category_plan` (`room_category_id`, `meal_plan_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE `hotel_bookings`
+ADD COLUMN `meal_plan_id` bigint unsigned DEFAULT NULL AFTER `discount_amount`,
+ADD COLUMN `meal_plan_amount` double DEFAULT 0 AFTER `meal_plan_id`;
@@ -0,0 +1,28 @@
+-- Migration: Multi-room booking orders
+-- Date: 2026-10-17
+-- Description: Groups hotel bookings made in one checkout under one reference and one Razorpay order
+
+CREATE TABLE IF NOT EXISTS `booking_orders` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+    `order_reference` varchar(20) NOT NULL,
+    `user_id` bigint unsigned DEFAULT NULL,
+    `guest_name` varchar(255) NOT NULL,
+    `guest_email` varchar(255) NOT NULL,
+    `guest_phone` varchar(20) NOT NULL,
+    `total_amount` double DEFAULT NULL,
+    `tax_amount` double DEFAULT NULL,
+    `final_amount` double DEFAULT NULL,
+    `payment_status` varchar(20) DEFAULT 'pending',
+    `razorpay_order_id` varchar(64) DEFAULT NULL,
+    `payment_id` varchar(255) DEFAULT NULL,
+    `payment_method` varchar(255) DEFAULT NULL,
+    `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_booking_orders_order_reference` (`order_reference`),
+    KEY `idx_booking_orders_razorpay_order_id` (`razorpay_order_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE `hotel_bookings`
+ADD COLUMN `order_id` bigint unsigned DEFAULT NULL AFTER `user_id`,
+ADD KEY `idx_hotel_bookings_order_id` (`order_id`);
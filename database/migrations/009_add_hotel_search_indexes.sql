@@ -0,0 +1,9 @@
+-- Migration: Indexes for hotel availability search
+-- Date: 2026-10-17
+-- Description: Search finds the active rooms of a city's hotels in one query
+
+ALTER TABLE `hotels`
+ADD KEY `idx_city_status` (`city_id`, `status`);
+
+ALTER TABLE `rooms`
+ADD KEY `idx_hotel_status` (`hotel_id`, `status`);
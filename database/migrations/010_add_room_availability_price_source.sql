@@ -0,0 +1,14 @@
+-- Migration: Rolling room availability calendar
+-- Date: 2026-10-17
+-- Description: Tag where each availability price came from and allow one row per room and night
+
+ALTER TABLE `room_availability`
+ADD COLUMN `price_source` VARCHAR(20) NOT NULL DEFAULT 'manual' AFTER `price`;
+
+-- Keep the oldest row when a night was entered more than once
+DELETE a FROM `room_availability` a
+JOIN `room_availability` b ON a.room_id = b.room_id AND a.date = b.date AND a.id > b.id;
+
+ALTER TABLE `room_availability`
+DROP KEY `idx_room_date`,
+ADD UNIQUE KEY `idx_room_date` (`room_id`, `date`);
@@ -0,0 +1,32 @@
+-- Migration: Rate plans
+-- Date: 2026-10-17
+-- Description: Weekday, seasonal and fixed price rules per hotel or room category
+
+CREATE TABLE IF NOT EXISTS `rate_plans` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+    `hotel_id` bigint unsigned NOT NULL,
+    `room_category_id` bigint unsigned DEFAULT NULL COMMENT 'NULL for every category of the hotel',
+    `name` varchar(255) NOT NULL,
+    `description` text,
+    `status` bigint DEFAULT 0 COMMENT '0: Active, 1: Inactive',
+    `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    KEY `idx_rate_plans_hotel_id` (`hotel_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `rate_plan_rules` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+    `rate_plan_id` bigint unsigned NOT NULL,
+    `name` varchar(255) DEFAULT NULL,
+    `priority` bigint DEFAULT 0 COMMENT 'Highest priority rule covering a night applies',
+    `valid_from` date DEFAULT NULL,
+    `valid_to` date DEFAULT NULL,
+    `weekdays` json DEFAULT NULL COMMENT 'Array of weekdays, 0 = Sunday',
+    `adjustment` varchar(20) NOT NULL COMMENT 'percent, amount or fixed',
+    `value` decimal(10,2) NOT NULL,
+    `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    KEY `idx_rate_plan_rules_rate_plan_id` (`rate_plan_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,19 @@
+-- Migration: Stay restrictions
+-- Date: 2026-10-17
+-- Description: Minimum/maximum stay, closed-to-arrival, closed-to-departure and stop-sell per room category and date
+
+CREATE TABLE IF NOT EXISTS `stay_restrictions` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+    `hotel_id` bigint unsigned NOT NULL,
+    `room_category_id` bigint unsigned NOT NULL,
+    `date` date NOT NULL,
+    `min_stay` bigint DEFAULT 0 COMMENT '0 for no minimum',
+    `max_stay` bigint DEFAULT 0 COMMENT '0 for no maximum',
+    `closed_to_arrival` tinyint(1) DEFAULT 0,
+    `closed_to_departure` tinyint(1) DEFAULT 0,
+    `stop_sell` tinyint(1) DEFAULT 0,
+    `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_hotel_category_date` (`hotel_id`, `room_category_id`, `date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
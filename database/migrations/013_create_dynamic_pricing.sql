@@ -0,0 +1,34 @@
+-- Migration: Occupancy-based dynamic pricing
+-- Date: 2026-10-17
+-- Description: Per hotel or room category occupancy bands and an audit of the prices they set
+
+CREATE TABLE IF NOT EXISTS `dynamic_pricing_rules` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+    `hotel_id` bigint unsigned NOT NULL,
+    `room_category_id` bigint unsigned DEFAULT NULL COMMENT 'NULL for every category of the hotel',
+    `enabled` tinyint(1) DEFAULT 0,
+    `bands` json NOT NULL COMMENT 'Array of {minOccupancy, adjustPercent}',
+    `floor_price` decimal(10,2) DEFAULT 0 COMMENT '0 for no floor',
+    `ceiling_price` decimal(10,2) DEFAULT 0 COMMENT '0 for no ceiling',
+    `lookahead_days` bigint DEFAULT 90,
+    `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    KEY `idx_dynamic_pricing_rules_hotel_id` (`hotel_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `dynamic_price_changes` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+    `rule_id` bigint unsigned NOT NULL,
+    `hotel_id` bigint unsigned NOT NULL,
+    `room_category_id` bigint unsigned NOT NULL,
+    `room_id` bigint unsigned NOT NULL,
+    `date` date NOT NULL,
+    `occupancy` decimal(5,2) DEFAULT NULL COMMENT 'Percent of the category booked',
+    `adjust_percent` decimal(6,2) DEFAULT NULL,
+    `old_price` decimal(10,2) DEFAULT NULL,
+    `new_price` decimal(10,2) DEFAULT NULL,
+    `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    KEY `idx_hotel_date` (`hotel_id`, `date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,21 @@
+-- Migration: iCal inventory sync
+-- Date: 2026-10-17
+-- Description: External calendar feeds per room and the source of nights they block
+
+CREATE TABLE IF NOT EXISTS `ical_feeds` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+    `room_id` bigint unsigned NOT NULL,
+    `name` varchar(100) NOT NULL,
+    `url` varchar(1000) NOT NULL,
+    `status` bigint DEFAULT 0 COMMENT '0: Active, 1: Inactive',
+    `last_synced_at` datetime(3) DEFAULT NULL,
+    `last_error` text,
+    `last_conflicts` json DEFAULT NULL COMMENT 'Nights the feed blocks that are booked with us',
+    `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    KEY `idx_ical_feeds_room_id` (`room_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE `room_availability`
+ADD COLUMN `block_source` varchar(50) DEFAULT NULL COMMENT 'Set when an external calendar blocked the night' AFTER `price_source`;
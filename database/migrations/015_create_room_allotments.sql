@@ -0,0 +1,29 @@
+-- Migration: Room category allotments
+-- Date: 2026-10-17
+-- Description: Per-date room allotments per room category, and hotel bookings that take a room of an allotment and get their room assigned later
+
+CREATE TABLE IF NOT EXISTS `room_allotments` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+    `hotel_id` bigint unsigned NOT NULL,
+    `room_category_id` bigint unsigned NOT NULL,
+    `date` date NOT NULL,
+    `allotment` bigint NOT NULL DEFAULT 0,
+    `sold` bigint NOT NULL DEFAULT 0,
+    `release_days` bigint DEFAULT 0 COMMENT 'Unsold rooms go back to the hotel this many days before the night',
+    `price` double DEFAULT 0 COMMENT '0 to use the room rates of the category',
+    `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_allotment_hotel_category_date` (`hotel_id`, `room_category_id`, `date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE `hotel_bookings`
+MODIFY COLUMN `room_id` bigint unsigned DEFAULT NULL,
+ADD COLUMN `room_category_id` bigint unsigned DEFAULT NULL AFTER `room_id`,
+ADD COLUMN `inventory_type` varchar(20) DEFAULT 'room' AFTER `room_category_id`,
+ADD KEY `idx_hotel_bookings_room_category_id` (`room_category_id`);
+
+UPDATE `hotel_bookings` b
+JOIN `rooms` r ON r.id = b.room_id
+SET b.room_category_id = r.room_category_id
+WHERE b.room_category_id IS NULL;
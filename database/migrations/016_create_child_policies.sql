@@ -0,0 +1,17 @@
+-- Migration: Child policies
+-- Date: 2026-10-17
+-- Description: Per-hotel child age bands, and the child ages and child charge of hotel bookings
+
+CREATE TABLE IF NOT EXISTS `child_policies` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+    `hotel_id` bigint unsigned NOT NULL,
+    `bands` json NOT NULL COMMENT 'Array of {name, minAge, maxAge, nightlyPrice, countsTowardOccupancy}',
+    `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_child_policies_hotel_id` (`hotel_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE `hotel_bookings`
+ADD COLUMN `child_ages` json DEFAULT NULL AFTER `number_of_guests`,
+ADD COLUMN `child_amount` double DEFAULT 0 AFTER `meal_plan_amount`;
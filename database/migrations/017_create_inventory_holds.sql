@@ -0,0 +1,26 @@
+-- Migration: Inventory holds
+-- Date: 2026-10-17
+-- Description: Short-lived holds on room nights, allotments and package seats taken during checkout
+
+CREATE TABLE IF NOT EXISTS `inventory_holds` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+    `token` varchar(64) NOT NULL,
+    `inventory_type` varchar(20) NOT NULL COMMENT 'room, allotment or package',
+    `hotel_id` bigint unsigned DEFAULT NULL,
+    `room_id` bigint unsigned DEFAULT NULL,
+    `room_category_id` bigint unsigned DEFAULT NULL,
+    `check_in_date` date DEFAULT NULL,
+    `check_out_date` date DEFAULT NULL,
+    `package_id` bigint unsigned DEFAULT NULL,
+    `travel_date` date DEFAULT NULL,
+    `seats` bigint DEFAULT 0,
+    `status` varchar(20) DEFAULT 'active',
+    `expires_at` datetime(3) NOT NULL,
+    `booking_id` bigint unsigned DEFAULT NULL COMMENT 'Hotel or package booking that took the hold over',
+    `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_inventory_holds_token` (`token`),
+    KEY `idx_inventory_holds_package_date` (`package_id`, `travel_date`),
+    KEY `idx_inventory_holds_status_expiry` (`status`, `expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,19 @@
+-- Migration: Idempotency keys
+-- Date: 2026-10-17
+-- Description: Requests made with an Idempotency-Key header and their responses, replayed when clients retry
+
+CREATE TABLE IF NOT EXISTS `idempotency_keys` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+    `key` varchar(255) NOT NULL,
+    `scope` varchar(255) NOT NULL COMMENT 'HTTP method and path the key was used on, and a SHA-256 of the caller''s Authorization header',
+    `fingerprint` varchar(64) NOT NULL COMMENT 'SHA-256 of the request body',
+    `status` varchar(20) DEFAULT 'processing',
+    `status_code` bigint DEFAULT 0,
+    `content_type` varchar(100) DEFAULT NULL,
+    `response` mediumblob,
+    `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_idempotency_key_scope` (`key`, `scope`),
+    KEY `idx_idempotency_keys_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,29 @@
+-- Migration: Booking soft delete and archive
+-- Date: 2026-10-17
+-- Description: Soft deletion of hotel bookings, hotel payments and package bookings, and archive tables for old hotel bookings with their guests and payments
+
+ALTER TABLE `hotel_bookings`
+ADD COLUMN `deleted_at` datetime(3) DEFAULT NULL,
+ADD KEY `idx_hotel_bookings_deleted_at` (`deleted_at`);
+
+ALTER TABLE `hotel_payments`
+ADD COLUMN `deleted_at` datetime(3) DEFAULT NULL,
+ADD KEY `idx_hotel_payments_deleted_at` (`deleted_at`);
+
+ALTER TABLE `package_bookings`
+ADD COLUMN `deleted_at` datetime(3) DEFAULT NULL,
+ADD KEY `idx_package_bookings_deleted_at` (`deleted_at`);
+
+-- Archive tables mirror their live tables; columns added to a live table must be added to its archive too
+CREATE TABLE IF NOT EXISTS `hotel_bookings_archive` LIKE `hotel_bookings`;
+ALTER TABLE `hotel_bookings_archive`
+ADD COLUMN `archived_at` datetime(3) DEFAULT NULL,
+ADD KEY `idx_hotel_bookings_archive_archived_at` (`archived_at`);
+
+CREATE TABLE IF NOT EXISTS `hotel_guests_archive` LIKE `hotel_guests`;
+ALTER TABLE `hotel_guests_archive`
+ADD COLUMN `archived_at` datetime(3) DEFAULT NULL;
+
+CREATE TABLE IF NOT EXISTS `hotel_payments_archive` LIKE `hotel_payments`;
+ALTER TABLE `hotel_payments_archive`
+ADD COLUMN `archived_at` datetime(3) DEFAULT NULL;
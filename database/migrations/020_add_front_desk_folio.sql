@@ -0,0 +1,33 @@
+-- Migration: Front desk check-in, check-out and folio
+-- Date: 2026-10-17
+-- Description: Actual arrival and departure times of hotel bookings, and folio charges for incidentals posted during the stay
+
+ALTER TABLE `hotel_bookings`
+ADD COLUMN `checked_in_at` datetime(3) DEFAULT NULL,
+ADD COLUMN `checked_out_at` datetime(3) DEFAULT NULL,
+ADD COLUMN `incidentals_amount` double DEFAULT 0,
+ADD COLUMN `folio_closed_at` datetime(3) DEFAULT NULL;
+
+ALTER TABLE `hotel_bookings_archive`
+ADD COLUMN `checked_in_at` datetime(3) DEFAULT NULL,
+ADD COLUMN `checked_out_at` datetime(3) DEFAULT NULL,
+ADD COLUMN `incidentals_amount` double DEFAULT 0,
+ADD COLUMN `folio_closed_at` datetime(3) DEFAULT NULL;
+
+CREATE TABLE IF NOT EXISTS `folio_charges` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+    `booking_id` bigint unsigned NOT NULL,
+    `category` varchar(50) DEFAULT NULL COMMENT 'e.g. food, laundry, minibar',
+    `description` longtext NOT NULL,
+    `quantity` bigint DEFAULT 1,
+    `unit_price` double DEFAULT NULL,
+    `amount` double DEFAULT NULL,
+    `posted_by` varchar(100) DEFAULT NULL,
+    `created_at` datetime(3) DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_folio_charges_booking_id` (`booking_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `folio_charges_archive` LIKE `folio_charges`;
+ALTER TABLE `folio_charges_archive`
+ADD COLUMN `archived_at` datetime(3) DEFAULT NULL;
@@ -0,0 +1,12 @@
+-- Migration: Razorpay order of a hotel booking
+-- Date: 2026-10-17
+-- Description: The Razorpay order created server-side to pay a hotel booking and its amount in paise, checked when the payment is verified
+
+ALTER TABLE `hotel_bookings`
+ADD COLUMN `razorpay_order_id` varchar(64) DEFAULT NULL,
+ADD COLUMN `razorpay_order_amount` bigint DEFAULT 0,
+ADD INDEX `idx_hotel_bookings_razorpay_order_id` (`razorpay_order_id`);
+
+ALTER TABLE `hotel_bookings_archive`
+ADD COLUMN `razorpay_order_id` varchar(64) DEFAULT NULL,
+ADD COLUMN `razorpay_order_amount` bigint DEFAULT 0;
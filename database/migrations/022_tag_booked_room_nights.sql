@@ -0,0 +1,28 @@
+-- Migration: Tag room nights closed by bookings and by hand
+-- Date: 2026-10-17
+-- Description: Tags closed room_availability nights with the block_source that closed them, so releasing a booking or checkout hold only reopens its own nights and never a night an iCal feed or a manual close still blocks
+
+UPDATE `room_availability` ra
+JOIN `hotel_bookings` b
+    ON b.`room_id` = ra.`room_id`
+    AND b.`booking_status` IN ('pending', 'confirmed', 'checked_in')
+    AND b.`deleted_at` IS NULL
+    AND ra.`date` >= DATE(b.`check_in_date`)
+    AND ra.`date` < DATE(b.`check_out_date`)
+SET ra.`block_source` = 'booking'
+WHERE ra.`is_available` = 0 AND ra.`block_source` IS NULL;
+
+UPDATE `room_availability` ra
+JOIN `inventory_holds` h
+    ON h.`room_id` = ra.`room_id`
+    AND h.`inventory_type` = 'room'
+    AND h.`status` = 'active'
+    AND ra.`date` >= DATE(h.`check_in_date`)
+    AND ra.`date` < DATE(h.`check_out_date`)
+SET ra.`block_source` = 'booking'
+WHERE ra.`is_available` = 0 AND ra.`block_source` IS NULL;
+
+-- Every other closed night was closed by hand
+UPDATE `room_availability`
+SET `block_source` = 'manual'
+WHERE `is_available` = 0 AND `block_source` IS NULL;
@@ -0,0 +1,6 @@
+-- Migration: Razorpay order amount of booking orders
+-- Date: 2026-10-17
+-- Description: Amount in paise the Razorpay order of a booking order was created for, checked when its payment is verified
+
+ALTER TABLE `booking_orders`
+ADD COLUMN `razorpay_order_amount` bigint DEFAULT 0 AFTER `updated_at`;
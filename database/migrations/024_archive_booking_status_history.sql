@@ -0,0 +1,11 @@
+-- Migration: Archive booking status history
+-- Date: 2026-10-17
+-- Description: Archive table for the status history of archived hotel bookings, and reviews that outlive their archived booking
+
+CREATE TABLE IF NOT EXISTS `booking_status_history_archive` LIKE `booking_status_history`;
+ALTER TABLE `booking_status_history_archive`
+ADD COLUMN `archived_at` datetime(3) DEFAULT NULL;
+
+-- Archiving a booking clears the booking_id of its reviews
+ALTER TABLE `hotel_reviews`
+MODIFY COLUMN `booking_id` bigint unsigned DEFAULT NULL;
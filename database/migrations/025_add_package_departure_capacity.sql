@@ -0,0 +1,6 @@
+-- Migration: Package departure capacity
+-- Date: 2026-10-17
+-- Description: Seats on each departure of a holiday package, kept apart from max_passengers; existing packages get 0, which means no limit
+
+ALTER TABLE `holiday_packages`
+ADD COLUMN `departure_capacity` int NOT NULL DEFAULT 0 COMMENT 'Seats on each departure, 0 for no limit';
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -30,6 +32,27 @@ type Config struct {
 
 	// External Services
 	NodeBackendURL string
+
+	// Hotel Bookings
+	HotelTaxPercent float64
+	ICalFeedSecret  string
+
+	// Admin
+	AdminAPIKey string
+
+	// Background Jobs
+	PendingBookingTTL          time.Duration
+	BookingExpiryInterval      time.Duration
+	AvailabilityHorizonDays    int
+	CalendarGenerationInterval time.Duration
+	DynamicPricingInterval     time.Duration
+	ICalSyncInterval           time.Duration
+	InventoryHoldTTL           time.Duration
+	HoldSweepInterval          time.Duration
+	IdempotencyKeyTTL          time.Duration
+	IdempotencyPurgeInterval   time.Duration
+	BookingRetentionDays       int
+	BookingArchiveInterval     time.Duration
 }
 
 // GetDatabaseDSN returns the MySQL DSN connection string
@@ -80,6 +103,27 @@ func Load() *Config {
 
 		// External Services
 		NodeBackendURL: getEnv("NODE_BACKEND_URL", "http://localhost:3001"),
+
+		// Hotel Bookings
+		HotelTaxPercent: getEnvFloat("HOTEL_TAX_PERCENT", 12),
+		ICalFeedSecret:  getEnv("ICAL_FEED_SECRET", ""),
+
+		// Admin
+		AdminAPIKey: getEnv("ADMIN_API_KEY", ""),
+
+		// Background Jobs
+		PendingBookingTTL:          getEnvDuration("PENDING_BOOKING_TTL", 30*time.Minute),
+		BookingExpiryInterval:      getEnvDuration("BOOKING_EXPIRY_INTERVAL", time.Minute),
+		AvailabilityHorizonDays:    getEnvInt("AVAILABILITY_HORIZON_DAYS", 365),
+		CalendarGenerationInterval: getEnvDuration("CALENDAR_GENERATION_INTERVAL", 24*time.Hour),
+		DynamicPricingInterval:     getEnvDuration("DYNAMIC_PRICING_INTERVAL", time.Hour),
+		ICalSyncInterval:           getEnvDuration("ICAL_SYNC_INTERVAL", 30*time.Minute),
+		InventoryHoldTTL:           getEnvDuration("INVENTORY_HOLD_TTL", 10*time.Minute),
+		HoldSweepInterval:          getEnvDuration("HOLD_SWEEP_INTERVAL", time.Minute),
+		IdempotencyKeyTTL:          getEnvDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour),
+		IdempotencyPurgeInterval:   getEnvDuration("IDEMPOTENCY_PURGE_INTERVAL", time.Hour),
+		BookingRetentionDays:       getEnvInt("BOOKING_RETENTION_DAYS", 730),
+		BookingArchiveInterval:     getEnvDuration("BOOKING_ARCHIVE_INTERVAL", 24*time.Hour),
 	}
 
 	// Debug logging (don't log secrets in production)
@@ -104,3 +148,42 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("⚠️  Warning: Invalid value for %s (%q), using default %v", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("⚠️  Warning: Invalid value for %s (%q), using default %v", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("⚠️  Warning: Invalid duration for %s (%q), using default %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
@@ -17,6 +17,8 @@ func Initialize(databaseURL string) (*gorm.DB, error) {
 		NowFunc: func() time.Time {
 			return time.Now().Local()
 		},
+		// Report unique key violations as gorm.ErrDuplicatedKey
+		TranslateError: true,
 	}
 
 	// Open database connection
@@ -57,6 +59,31 @@ func Initialize(databaseURL string) (*gorm.DB, error) {
 		log.Println("✅ Holiday package models migrated successfully")
 	}
 
+	// Auto-migrate hotel booking support tables. These models carry no associations so the
+	// hotel tables themselves are left alone (see database/migrations for their changes).
+	err = db.AutoMigrate(
+		&models.BookingStatusHistory{},
+		&models.CancellationPolicy{},
+		&models.MealPlanRate{},
+		&models.BookingOrder{},
+		&models.RatePlan{},
+		&models.RatePlanRule{},
+		&models.StayRestriction{},
+		&models.RoomAllotment{},
+		&models.ChildPolicy{},
+		&models.DynamicPricingRule{},
+		&models.DynamicPriceChange{},
+		&models.ICalFeed{},
+		&models.InventoryHold{},
+		&models.IdempotencyKey{},
+		&models.FolioCharge{},
+	)
+	if err != nil {
+		log.Printf("Warning: Failed to auto-migrate hotel booking support models: %v", err)
+	} else {
+		log.Println("✅ Hotel booking support models migrated successfully")
+	}
+
 	// Auto-migrate models (disabled for now to avoid schema conflicts)
 	// err = db.AutoMigrate(
 	// 	&models.City{},
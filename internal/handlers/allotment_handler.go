@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"errors"
+	"flyola-services/internal/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AllotmentHandler struct {
+	allotmentService *services.AllotmentService
+}
+
+func NewAllotmentHandler(allotmentService *services.AllotmentService) *AllotmentHandler {
+	return &AllotmentHandler{allotmentService: allotmentService}
+}
+
+// GetHotelAllotments lists a hotel's room allotments from ?from= to ?to=, optionally for one
+// ?room_category_id=
+func (h *AllotmentHandler) GetHotelAllotments(c *gin.Context) {
+	hotelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hotel ID"})
+		return
+	}
+
+	from, err := time.ParseInLocation("2006-01-02", c.Query("from"), time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from is required (YYYY-MM-DD)"})
+		return
+	}
+	to, err := time.ParseInLocation("2006-01-02", c.Query("to"), time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to is required (YYYY-MM-DD)"})
+		return
+	}
+
+	var roomCategoryID *uint
+	if value := c.Query("room_category_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room_category_id"})
+			return
+		}
+		categoryID := uint(id)
+		roomCategoryID = &categoryID
+	}
+
+	allotments, err := h.allotmentService.GetAllotments(uint(hotelID), roomCategoryID, from, to)
+	if err != nil {
+		respondAllotmentError(c, err, "Failed to fetch allotments")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": allotments})
+}
+
+func (h *AllotmentHandler) SetHotelAllotments(c *gin.Context) {
+	hotelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hotel ID"})
+		return
+	}
+
+	var update services.AllotmentUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	dates, err := h.allotmentService.SetAllotments(uint(hotelID), &update)
+	if err != nil {
+		respondAllotmentError(c, err, "Failed to update allotments")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Allotments updated successfully", "data": gin.H{"dates": dates}})
+}
+
+func (h *AllotmentHandler) DeleteAllotment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid allotment ID"})
+		return
+	}
+
+	if err := h.allotmentService.DeleteAllotment(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Allotment not found"})
+			return
+		}
+		respondAllotmentError(c, err, "Failed to delete allotment")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Allotment deleted successfully"})
+}
+
+func respondAllotmentError(c *gin.Context, err error, fallback string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Hotel or room category not found"})
+		return
+	}
+
+	var validationErr *services.BookingValidationError
+	if errors.As(err, &validationErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+}
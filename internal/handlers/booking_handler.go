@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"errors"
 	"flyola-services/internal/models"
 	"flyola-services/internal/services"
+	"io"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type BookingHandler struct {
@@ -18,25 +22,88 @@ func NewBookingHandler(bookingService *services.BookingService) *BookingHandler
 }
 
 func (h *BookingHandler) GetBookings(c *gin.Context) {
-	// Check if email query parameter is provided
-	email := c.Query("email")
+	// Guests look up their own booking by reference and email instead of listing by email
+	if c.Query("email") != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Filtering by email is not supported. Use /bookings/lookup with reference and email."})
+		return
+	}
+	h.listBookings(c, false)
+}
 
-	var bookings []models.HotelBooking
-	var err error
+// GetArchivedBookings lists archived bookings for reporting, with the same filters as GetBookings
+func (h *BookingHandler) GetArchivedBookings(c *gin.Context) {
+	h.listBookings(c, true)
+}
 
-	if email != "" {
-		// Filter by guest email if provided
-		bookings, err = h.bookingService.GetBookingsByGuestEmail(email)
-	} else {
-		// Get all bookings if no email filter
-		bookings, err = h.bookingService.GetAllBookings()
+func (h *BookingHandler) listBookings(c *gin.Context, archived bool) {
+	filter, err := parseBookingListFilter(c)
+	if err == nil && c.Query("hotel_id") != "" {
+		filter.HotelID, err = parseOptionalID(c.Query("hotel_id"), "hotel_id")
+	}
+	if err == nil && filter.Status != "" && !models.IsBookingStatus(filter.Status) {
+		err = &services.BookingValidationError{Message: "Unknown booking status " + filter.Status}
+	}
+	if err != nil {
+		respondBookingError(c, err)
+		return
 	}
 
+	filter.Archived = archived
+	bookings, page, err := h.bookingService.ListBookings(filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookings"})
+		if !respondBookingError(c, err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookings"})
+		}
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Bookings retrieved successfully", "data": bookings})
+	c.JSON(http.StatusOK, gin.H{"message": "Bookings retrieved successfully", "data": bookings, "pagination": page})
+}
+
+// parseBookingListFilter reads the filters shared by hotel and package booking lists:
+// ?status=&payment_status=&from=&to=&q=&sort=&cursor=&limit=
+func parseBookingListFilter(c *gin.Context) (*services.BookingListFilter, error) {
+	filter := &services.BookingListFilter{
+		Status:        c.Query("status"),
+		PaymentStatus: c.Query("payment_status"),
+		Search:        c.Query("q"),
+		Sort:          c.Query("sort"),
+		Cursor:        c.Query("cursor"),
+	}
+
+	for _, param := range []string{"from", "to"} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			return nil, &services.BookingValidationError{Message: "Invalid " + param + " (YYYY-MM-DD)"}
+		}
+		if param == "from" {
+			filter.From = &date
+		} else {
+			filter.To = &date
+		}
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return nil, &services.BookingValidationError{Message: "limit must be a positive number"}
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
+
+// parseOptionalID parses the ID given in a query parameter
+func parseOptionalID(value, param string) (*uint, error) {
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return nil, &services.BookingValidationError{Message: "Invalid " + param}
+	}
+	parsed := uint(id)
+	return &parsed, nil
 }
 
 func (h *BookingHandler) GetBookingByID(c *gin.Context) {
@@ -54,6 +121,23 @@ func (h *BookingHandler) GetBookingByID(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Booking retrieved successfully", "data": booking})
 }
 
+// LookupBooking returns a single booking to a guest who knows both its reference and email
+func (h *BookingHandler) LookupBooking(c *gin.Context) {
+	reference := c.Query("reference")
+	email := c.Query("email")
+	if reference == "" || email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reference and email are required"})
+		return
+	}
+
+	booking, err := h.bookingService.LookupBooking(reference, email)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Booking retrieved successfully", "data": booking})
+}
+
 func (h *BookingHandler) CreateBooking(c *gin.Context) {
 	var booking models.HotelBooking
 	if err := c.ShouldBindJSON(&booking); err != nil {
@@ -61,7 +145,10 @@ func (h *BookingHandler) CreateBooking(c *gin.Context) {
 		return
 	}
 
-	if err := h.bookingService.CreateBooking(&booking); err != nil {
+	if err := h.bookingService.CreateBooking(&booking, requestActor(c, "guest")); err != nil {
+		if respondBookingError(c, err) {
+			return
+		}
 		// Log the actual error for debugging
 		println("Error creating booking:", err.Error())
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking", "details": err.Error()})
@@ -70,6 +157,25 @@ func (h *BookingHandler) CreateBooking(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{"message": "Booking created successfully", "data": booking})
 }
 
+// QuoteBooking returns the server-computed price breakdown for a booking request
+func (h *BookingHandler) QuoteBooking(c *gin.Context) {
+	var booking models.HotelBooking
+	if err := c.ShouldBindJSON(&booking); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	breakdown, err := h.bookingService.QuoteBooking(&booking)
+	if err != nil {
+		if respondBookingError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to price booking", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Booking priced successfully", "data": breakdown})
+}
+
 func (h *BookingHandler) UpdateBooking(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -98,6 +204,183 @@ func (h *BookingHandler) UpdateBooking(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Booking updated successfully", "data": booking})
 }
 
+// ModifyBooking changes the room, dates or guest count of a booking and returns the price difference
+func (h *BookingHandler) ModifyBooking(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	var modification services.BookingModification
+	if err := c.ShouldBindJSON(&modification); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	result, err := h.bookingService.ModifyBooking(uint(id), &modification, requestActor(c, "guest"))
+	if err != nil {
+		if respondBookingError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to modify booking"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Booking modified successfully", "data": result})
+}
+
+// AssignRoom gives a category booking its room, at the latest when the guest checks in
+func (h *BookingHandler) AssignRoom(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	var req struct {
+		RoomID uint `json:"room_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	booking, err := h.bookingService.AssignRoom(uint(id), req.RoomID, requestActor(c, "front_desk"))
+	if err != nil {
+		if respondBookingError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign room"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Room assigned successfully", "data": booking})
+}
+
+// CheckIn records a guest's arrival at the front desk
+func (h *BookingHandler) CheckIn(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	var req services.CheckInRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	booking, err := h.bookingService.CheckIn(uint(id), &req, requestActor(c, "front_desk"))
+	if err != nil {
+		if respondBookingError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check in"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Guest checked in successfully", "data": booking})
+}
+
+// CheckOut closes a guest's folio and records their departure
+func (h *BookingHandler) CheckOut(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	var req services.CheckOutRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	booking, folio, err := h.bookingService.CheckOut(uint(id), &req, requestActor(c, "front_desk"))
+	if err != nil {
+		if respondBookingError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check out"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Guest checked out successfully", "data": gin.H{"booking": booking, "folio": folio}})
+}
+
+// GetFolio returns the charges and balance of a booking
+func (h *BookingHandler) GetFolio(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	folio, err := h.bookingService.GetFolio(uint(id))
+	if err != nil {
+		if respondBookingError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch folio"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Folio retrieved successfully", "data": folio})
+}
+
+// PostFolioCharge charges an incidental to a checked-in guest
+func (h *BookingHandler) PostFolioCharge(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	var req services.FolioChargeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	charge, err := h.bookingService.PostFolioCharge(uint(id), &req, requestActor(c, "front_desk"))
+	if err != nil {
+		if respondBookingError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to post charge"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Charge posted successfully", "data": charge})
+}
+
+// GetFrontDeskList returns a hotel's arrivals, departures and in-house guests for ?date=YYYY-MM-DD,
+// today by default
+func (h *BookingHandler) GetFrontDeskList(c *gin.Context) {
+	hotelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hotel ID"})
+		return
+	}
+
+	date := time.Now()
+	if value := c.Query("date"); value != "" {
+		date, err = time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date, expected YYYY-MM-DD"})
+			return
+		}
+	}
+
+	list, err := h.bookingService.GetFrontDeskList(uint(hotelID), date)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Hotel not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch front desk list"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Front desk list retrieved successfully", "data": list})
+}
+
+// DeleteBooking soft deletes a booking and gives back its inventory (Admin only)
 func (h *BookingHandler) DeleteBooking(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -106,12 +389,34 @@ func (h *BookingHandler) DeleteBooking(c *gin.Context) {
 	}
 
 	if err := h.bookingService.DeleteBooking(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete booking"})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Booking deleted successfully"})
 }
 
+// RestoreBooking brings back a deleted booking (Admin only)
+func (h *BookingHandler) RestoreBooking(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	booking, err := h.bookingService.RestoreBooking(uint(id))
+	if err != nil {
+		if !respondBookingError(c, err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore booking"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Booking restored successfully", "data": booking})
+}
+
 func (h *BookingHandler) CancelBooking(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -119,10 +424,189 @@ func (h *BookingHandler) CancelBooking(c *gin.Context) {
 		return
 	}
 
-	booking, err := h.bookingService.CancelBooking(uint(id))
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	booking, quote, err := h.bookingService.CancelBooking(uint(id), requestActor(c, "guest"), req.Reason)
 	if err != nil {
+		if respondBookingError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel booking"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Booking cancelled successfully", "data": booking})
+	c.JSON(http.StatusOK, gin.H{"message": "Booking cancelled successfully", "data": booking, "cancellation": quote})
+}
+
+// PreviewCancellation shows the refund a booking would get if it were cancelled now
+func (h *BookingHandler) PreviewCancellation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	quote, err := h.bookingService.PreviewCancellation(uint(id))
+	if err != nil {
+		if respondBookingError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute cancellation refund"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Cancellation preview computed successfully", "data": quote})
+}
+
+// UpdateBookingStatus moves a booking along its lifecycle (e.g. marking a no-show)
+func (h *BookingHandler) UpdateBookingStatus(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	var req struct {
+		Status string `json:"status" binding:"required"`
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	if !models.IsBookingStatus(req.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown booking status"})
+		return
+	}
+
+	// Confirmation only happens once the payment has been verified
+	if req.Status == models.BookingStatusConfirmed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Direct status confirmation not allowed. Use payment verification flow."})
+		return
+	}
+
+	// Cancellation applies the cancellation policy and records the refund
+	if req.Status == models.BookingStatusCancelled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Use PUT /bookings/:id/cancel to cancel a booking"})
+		return
+	}
+
+	// Arrival and departure record guest IDs and settle the folio at the front desk
+	if req.Status == models.BookingStatusCheckedIn || req.Status == models.BookingStatusCheckedOut {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Use /bookings/:id/check-in or /bookings/:id/check-out to change this status"})
+		return
+	}
+
+	booking, err := h.bookingService.TransitionBooking(uint(id), req.Status, requestActor(c, "admin"), req.Reason)
+	if err != nil {
+		if respondBookingError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking status"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Booking status updated successfully", "data": booking})
+}
+
+// GetBookingHistory returns the status transitions of a booking
+func (h *BookingHandler) GetBookingHistory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	history, err := h.bookingService.GetBookingHistory(uint(id))
+	if err != nil {
+		if respondBookingError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch booking history"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Booking history retrieved successfully", "data": history})
+}
+
+// respondBookingError writes the response for errors the booking service reports about the
+// request itself. It returns false for anything else so the caller can treat it as a server error.
+func respondBookingError(c *gin.Context, err error) bool {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return true
+	}
+
+	var validationErr *services.BookingValidationError
+	if errors.As(err, &validationErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Message})
+		return true
+	}
+
+	var mismatchErr *services.PriceMismatchError
+	if errors.As(err, &mismatchErr) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":    "Booking amounts do not match the computed price",
+			"details":  mismatchErr.Error(),
+			"computed": mismatchErr.Breakdown,
+		})
+		return true
+	}
+
+	var conflictErr *services.BookingConflictError
+	if errors.As(err, &conflictErr) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Room is not available for the selected dates",
+			"details": conflictErr.Error(),
+			"dates":   conflictErr.Dates,
+		})
+		return true
+	}
+
+	var allotmentErr *services.AllotmentUnavailableError
+	if errors.As(err, &allotmentErr) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "No rooms of the category are left for the selected dates",
+			"details": allotmentErr.Error(),
+			"dates":   allotmentErr.Dates,
+		})
+		return true
+	}
+
+	var holdErr *services.HoldUnavailableError
+	if errors.As(err, &holdErr) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Hold can no longer be used", "details": holdErr.Error()})
+		return true
+	}
+
+	var restrictionErr *services.RestrictionViolationError
+	if errors.As(err, &restrictionErr) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   restrictionErr.Message,
+			"rule":    restrictionErr.Rule,
+			"date":    restrictionErr.Date,
+			"details": "The stay breaks a stay restriction of the room category",
+		})
+		return true
+	}
+
+	var transitionErr *services.InvalidTransitionError
+	if errors.As(err, &transitionErr) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Booking status change not allowed", "details": transitionErr.Error()})
+		return true
+	}
+
+	return false
+}
+
+// requestActor identifies who is making a request for audit records. Callers identify
+// themselves with the X-Actor header; fallback is used when it is absent.
+func requestActor(c *gin.Context, fallback string) string {
+	if actor := c.GetHeader("X-Actor"); actor != "" {
+		return actor
+	}
+	return fallback
 }
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"flyola-services/internal/models"
+	"flyola-services/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CancellationPolicyHandler struct {
+	policyService *services.CancellationPolicyService
+}
+
+func NewCancellationPolicyHandler(policyService *services.CancellationPolicyService) *CancellationPolicyHandler {
+	return &CancellationPolicyHandler{policyService: policyService}
+}
+
+type cancellationPolicyRequest struct {
+	Rules []models.CancellationRule `json:"rules" binding:"required"`
+}
+
+func (h *CancellationPolicyHandler) GetHotelPolicy(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hotel ID"})
+		return
+	}
+
+	policy, err := h.policyService.GetHotelPolicy(uint(id))
+	if err != nil {
+		respondPolicyError(c, err, "Hotel not found")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Cancellation policy retrieved successfully", "data": policy})
+}
+
+func (h *CancellationPolicyHandler) SetHotelPolicy(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hotel ID"})
+		return
+	}
+
+	var req cancellationPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	policy, err := h.policyService.SetHotelPolicy(uint(id), req.Rules)
+	if err != nil {
+		respondPolicyError(c, err, "Hotel not found")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Cancellation policy updated successfully", "data": policy})
+}
+
+func (h *CancellationPolicyHandler) GetPackagePolicy(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid package ID"})
+		return
+	}
+
+	policy, err := h.policyService.GetPackagePolicy(uint(id))
+	if err != nil {
+		respondPolicyError(c, err, "Package not found")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Cancellation policy retrieved successfully", "data": policy})
+}
+
+func (h *CancellationPolicyHandler) SetPackagePolicy(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid package ID"})
+		return
+	}
+
+	var req cancellationPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	policy, err := h.policyService.SetPackagePolicy(uint(id), req.Rules)
+	if err != nil {
+		respondPolicyError(c, err, "Package not found")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Cancellation policy updated successfully", "data": policy})
+}
+
+func respondPolicyError(c *gin.Context, err error, notFound string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+		return
+	}
+
+	var validationErr *services.BookingValidationError
+	if errors.As(err, &validationErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process cancellation policy"})
+}
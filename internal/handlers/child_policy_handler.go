@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+	"flyola-services/internal/models"
+	"flyola-services/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ChildPolicyHandler struct {
+	childPolicyService *services.ChildPolicyService
+}
+
+func NewChildPolicyHandler(childPolicyService *services.ChildPolicyService) *ChildPolicyHandler {
+	return &ChildPolicyHandler{childPolicyService: childPolicyService}
+}
+
+type childPolicyRequest struct {
+	Bands []models.ChildAgeBand `json:"bands" binding:"required"`
+}
+
+func (h *ChildPolicyHandler) GetHotelPolicy(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hotel ID"})
+		return
+	}
+
+	policy, err := h.childPolicyService.GetHotelPolicy(uint(id))
+	if err != nil {
+		respondChildPolicyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Child policy retrieved successfully", "data": policy})
+}
+
+func (h *ChildPolicyHandler) SetHotelPolicy(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hotel ID"})
+		return
+	}
+
+	var req childPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	policy, err := h.childPolicyService.SetHotelPolicy(uint(id), req.Bands)
+	if err != nil {
+		respondChildPolicyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Child policy updated successfully", "data": policy})
+}
+
+func (h *ChildPolicyHandler) DeleteHotelPolicy(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hotel ID"})
+		return
+	}
+
+	if err := h.childPolicyService.DeleteHotelPolicy(uint(id)); err != nil {
+		respondChildPolicyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Child policy deleted successfully"})
+}
+
+func respondChildPolicyError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Hotel not found"})
+		return
+	}
+
+	var validationErr *services.BookingValidationError
+	if errors.As(err, &validationErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process child policy"})
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"errors"
+	"flyola-services/internal/models"
+	"flyola-services/internal/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type DynamicPricingHandler struct {
+	dynamicPricingService *services.DynamicPricingService
+}
+
+func NewDynamicPricingHandler(dynamicPricingService *services.DynamicPricingService) *DynamicPricingHandler {
+	return &DynamicPricingHandler{dynamicPricingService: dynamicPricingService}
+}
+
+type dynamicPricingRuleRequest struct {
+	RoomCategoryID *uint                  `json:"roomCategoryId"`
+	Enabled        bool                   `json:"enabled"`
+	Bands          []models.OccupancyBand `json:"bands" binding:"required"`
+	FloorPrice     float64                `json:"floorPrice"`
+	CeilingPrice   float64                `json:"ceilingPrice"`
+	LookaheadDays  int                    `json:"lookaheadDays"`
+}
+
+func (h *DynamicPricingHandler) GetHotelRules(c *gin.Context) {
+	hotelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hotel ID"})
+		return
+	}
+
+	rules, err := h.dynamicPricingService.GetHotelRules(uint(hotelID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch dynamic pricing rules"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": rules})
+}
+
+func (h *DynamicPricingHandler) SetRule(c *gin.Context) {
+	hotelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hotel ID"})
+		return
+	}
+
+	var req dynamicPricingRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	rule := models.DynamicPricingRule{
+		RoomCategoryID: req.RoomCategoryID,
+		Enabled:        req.Enabled,
+		FloorPrice:     req.FloorPrice,
+		CeilingPrice:   req.CeilingPrice,
+		LookaheadDays:  req.LookaheadDays,
+	}
+	if err := rule.SetBands(req.Bands); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bands"})
+		return
+	}
+
+	saved, err := h.dynamicPricingService.SetRule(uint(hotelID), &rule)
+	if err != nil {
+		respondDynamicPricingError(c, err, "Failed to save dynamic pricing rule")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Dynamic pricing rule saved successfully", "data": saved})
+}
+
+func (h *DynamicPricingHandler) DeleteRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dynamic pricing rule ID"})
+		return
+	}
+
+	if err := h.dynamicPricingService.DeleteRule(uint(id)); err != nil {
+		respondDynamicPricingError(c, err, "Failed to delete dynamic pricing rule")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Dynamic pricing rule deleted successfully"})
+}
+
+// PreviewPrices shows the prices the hotel's rules would set from ?from= up to ?to= without
+// changing anything
+func (h *DynamicPricingHandler) PreviewPrices(c *gin.Context) {
+	hotelID, from, to, ok := hotelDateRange(c)
+	if !ok {
+		return
+	}
+
+	suggestions, err := h.dynamicPricingService.PreviewHotel(hotelID, from, to)
+	if err != nil {
+		respondDynamicPricingError(c, err, "Failed to preview dynamic prices")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": suggestions})
+}
+
+// ApplyPrices reprices the hotel straight away instead of waiting for the scheduled job
+func (h *DynamicPricingHandler) ApplyPrices(c *gin.Context) {
+	hotelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hotel ID"})
+		return
+	}
+
+	changed, err := h.dynamicPricingService.ApplyHotel(uint(hotelID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply dynamic prices"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Dynamic prices applied successfully", "data": gin.H{"changed": changed}})
+}
+
+// GetPriceChanges lists the audit of prices set by dynamic pricing for nights from ?from= to ?to=
+func (h *DynamicPricingHandler) GetPriceChanges(c *gin.Context) {
+	hotelID, from, to, ok := hotelDateRange(c)
+	if !ok {
+		return
+	}
+
+	changes, err := h.dynamicPricingService.GetPriceChanges(hotelID, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch dynamic price changes"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": changes})
+}
+
+// hotelDateRange reads the hotel ID and the ?from=&to= dates of a request, responding with
+// 400 when one is invalid
+func hotelDateRange(c *gin.Context) (uint, time.Time, time.Time, bool) {
+	hotelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hotel ID"})
+		return 0, time.Time{}, time.Time{}, false
+	}
+	from, err := time.ParseInLocation("2006-01-02", c.Query("from"), time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from is required (YYYY-MM-DD)"})
+		return 0, time.Time{}, time.Time{}, false
+	}
+	to, err := time.ParseInLocation("2006-01-02", c.Query("to"), time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to is required (YYYY-MM-DD)"})
+		return 0, time.Time{}, time.Time{}, false
+	}
+	return uint(hotelID), from, to, true
+}
+
+func respondDynamicPricingError(c *gin.Context, err error, fallback string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dynamic pricing rule, hotel or room category not found"})
+		return
+	}
+
+	var validationErr *services.BookingValidationError
+	if errors.As(err, &validationErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+}
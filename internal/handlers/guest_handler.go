@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"errors"
+	"flyola-services/internal/models"
+	"flyola-services/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GuestHandler struct {
+	guestService *services.GuestService
+}
+
+func NewGuestHandler(guestService *services.GuestService) *GuestHandler {
+	return &GuestHandler{guestService: guestService}
+}
+
+func (h *GuestHandler) GetGuests(c *gin.Context) {
+	bookingID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	guests, err := h.guestService.GetGuests(uint(bookingID))
+	if err != nil {
+		if respondGuestError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch guests"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Guests retrieved successfully", "data": guests})
+}
+
+func (h *GuestHandler) AddGuest(c *gin.Context) {
+	bookingID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	var guest models.HotelGuest
+	if err := c.ShouldBindJSON(&guest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	if err := h.guestService.AddGuest(uint(bookingID), &guest); err != nil {
+		if respondGuestError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add guest"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Guest added successfully", "data": guest})
+}
+
+func (h *GuestHandler) UpdateGuest(c *gin.Context) {
+	bookingID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+	guestID, err := strconv.ParseUint(c.Param("guestId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid guest ID"})
+		return
+	}
+
+	var update services.GuestUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	guest, err := h.guestService.UpdateGuest(uint(bookingID), uint(guestID), &update)
+	if err != nil {
+		if respondGuestError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update guest"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Guest updated successfully", "data": guest})
+}
+
+func (h *GuestHandler) DeleteGuest(c *gin.Context) {
+	bookingID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+	guestID, err := strconv.ParseUint(c.Param("guestId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid guest ID"})
+		return
+	}
+
+	if err := h.guestService.DeleteGuest(uint(bookingID), uint(guestID)); err != nil {
+		if respondGuestError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete guest"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Guest deleted successfully"})
+}
+
+func respondGuestError(c *gin.Context, err error) bool {
+	if errors.Is(err, services.ErrGuestNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Guest not found"})
+		return true
+	}
+	return respondBookingError(c, err)
+}
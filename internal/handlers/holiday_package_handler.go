@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"flyola-services/internal/models"
 	"flyola-services/internal/services"
 	"log"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type HolidayPackageHandler struct {
@@ -125,6 +127,7 @@ func (h *HolidayPackageHandler) CreatePackageBooking(c *gin.Context) {
 		PaymentID       string                      `json:"payment_id"`
 		PaymentMethod   string                      `json:"payment_method"`
 		PaymentStatus   string                      `json:"payment_status"`
+		HoldToken       string                      `json:"hold_token"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -205,7 +208,24 @@ func (h *HolidayPackageHandler) CreatePackageBooking(c *gin.Context) {
 
 	log.Printf("🔍 Booking before save - PaymentID: %s, PaymentStatus: %s, BookingStatus: %s", booking.PaymentID, booking.PaymentStatus, booking.BookingStatus)
 
-	if err := h.service.CreatePackageBooking(booking); err != nil {
+	if err := h.service.CreatePackageBooking(booking, req.HoldToken); err != nil {
+		var validationErr *services.BookingValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   validationErr.Message,
+			})
+			return
+		}
+		var seatsErr *services.PackageSeatsError
+		var holdErr *services.HoldUnavailableError
+		if errors.As(err, &seatsErr) || errors.As(err, &holdErr) {
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"error":   "Seats are not available: " + err.Error(),
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error":   "Failed to create booking: " + err.Error(),
@@ -258,6 +278,21 @@ func (h *HolidayPackageHandler) ConfirmPackageBooking(c *gin.Context) {
 
 	// Update payment status
 	if err := h.service.UpdateBookingPaymentStatus(uint(id), req.PaymentID, req.PaymentMethod); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error":   "Booking not found",
+			})
+			return
+		}
+		var transitionErr *services.InvalidTransitionError
+		if errors.As(err, &transitionErr) {
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"error":   "Booking cannot be confirmed: " + transitionErr.Error(),
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error":   "Failed to update payment status: " + err.Error(),
@@ -306,6 +341,73 @@ func (h *HolidayPackageHandler) GetBookingByID(c *gin.Context) {
 	})
 }
 
+// DeletePackageBooking handles DELETE /api/v1/holiday-packages/admin/bookings/{id} (Admin only)
+func (h *HolidayPackageHandler) DeletePackageBooking(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid booking ID",
+		})
+		return
+	}
+
+	if err := h.service.DeletePackageBooking(uint(id)); err != nil {
+		status, message := http.StatusInternalServerError, "Failed to delete booking: "+err.Error()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			status, message = http.StatusNotFound, "Booking not found"
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"error":   message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Package booking deleted successfully",
+	})
+}
+
+// RestorePackageBooking handles POST /api/v1/holiday-packages/bookings/{id}/restore (Admin only)
+func (h *HolidayPackageHandler) RestorePackageBooking(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid booking ID",
+		})
+		return
+	}
+
+	booking, err := h.service.RestorePackageBooking(uint(id))
+	if err != nil {
+		var validationErr *services.BookingValidationError
+		var seatsErr *services.PackageSeatsError
+		status, message := http.StatusInternalServerError, "Failed to restore booking: "+err.Error()
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			status, message = http.StatusNotFound, "Booking not found"
+		case errors.As(err, &validationErr):
+			status, message = http.StatusBadRequest, validationErr.Message
+		case errors.As(err, &seatsErr):
+			status, message = http.StatusConflict, "Seats are not available: "+err.Error()
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"error":   message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    booking,
+		"message": "Package booking restored successfully",
+	})
+}
+
 // GetBookingByReference handles GET /api/v1/holiday-packages/bookings/reference/{reference}
 func (h *HolidayPackageHandler) GetBookingByReference(c *gin.Context) {
 	reference := c.Param("reference")
@@ -336,7 +438,11 @@ func (h *HolidayPackageHandler) CancelPackageBooking(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.CancelPackageBooking(uint(id)); err != nil {
+	quote, err := h.service.CancelPackageBooking(uint(id))
+	if err != nil {
+		if respondPackageCancellationError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error":   "Failed to cancel booking: " + err.Error(),
@@ -344,12 +450,63 @@ func (h *HolidayPackageHandler) CancelPackageBooking(c *gin.Context) {
 		return
 	}
 
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"message":      "Package booking cancelled successfully",
+		"cancellation": quote,
+	})
+}
+
+// PreviewPackageCancellation handles GET /api/v1/holiday-packages/bookings/{id}/cancellation-preview
+func (h *HolidayPackageHandler) PreviewPackageCancellation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid booking ID",
+		})
+		return
+	}
+
+	quote, err := h.service.PreviewPackageCancellation(uint(id))
+	if err != nil {
+		if respondPackageCancellationError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to compute cancellation refund: " + err.Error(),
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "Package booking cancelled successfully",
+		"data":    quote,
 	})
 }
 
+func respondPackageCancellationError(c *gin.Context, err error) bool {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "Booking not found",
+		})
+		return true
+	}
+
+	var transitionErr *services.InvalidTransitionError
+	if errors.As(err, &transitionErr) {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error":   "Booking cannot be cancelled: " + transitionErr.Error(),
+		})
+		return true
+	}
+
+	return false
+}
+
 // CreatePackage handles POST /api/v1/holiday-packages (Admin only)
 func (h *HolidayPackageHandler) CreatePackage(c *gin.Context) {
 	var pkg models.HolidayPackage
@@ -486,8 +643,28 @@ func (h *HolidayPackageHandler) DeletePackage(c *gin.Context) {
 
 // GetAllPackageBookings handles GET /api/v1/holiday-packages/admin/bookings (Admin only)
 func (h *HolidayPackageHandler) GetAllPackageBookings(c *gin.Context) {
-	bookings, err := h.service.GetAllPackageBookings()
+	filter, err := parseBookingListFilter(c)
+	if err == nil && c.Query("package_id") != "" {
+		filter.PackageID, err = parseOptionalID(c.Query("package_id"), "package_id")
+	}
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	bookings, page, err := h.service.ListPackageBookings(filter)
+	if err != nil {
+		var validationErr *services.BookingValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   validationErr.Message,
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error":   "Failed to fetch bookings: " + err.Error(),
@@ -496,7 +673,8 @@ func (h *HolidayPackageHandler) GetAllPackageBookings(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"data":    bookings,
+		"success":    true,
+		"data":       bookings,
+		"pagination": page,
 	})
 }
\ No newline at end of file
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"errors"
+	"flyola-services/internal/models"
+	"flyola-services/internal/services"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ICalHandler struct {
+	icalService *services.ICalService
+}
+
+func NewICalHandler(icalService *services.ICalService) *ICalHandler {
+	return &ICalHandler{icalService: icalService}
+}
+
+// ExportRoomCalendar serves a room's booked and blocked nights as an iCalendar feed for OTAs
+func (h *ICalHandler) ExportRoomCalendar(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+
+	calendar, err := h.icalService.ExportRoomCalendar(uint(roomID), c.Query("token"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidFeedToken):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Invalid calendar token"})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export room calendar"})
+		}
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=room-%d.ics", roomID))
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", calendar)
+}
+
+// GetRoomFeeds lists a room's external calendars along with the URL of its own export
+func (h *ICalHandler) GetRoomFeeds(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+
+	feeds, err := h.icalService.GetRoomFeeds(uint(roomID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch iCal feeds"})
+		return
+	}
+
+	exportURL := fmt.Sprintf("/api/v1/rooms/%d/calendar.ics", roomID)
+	if token := h.icalService.ExportToken(uint(roomID)); token != "" {
+		exportURL += "?token=" + token
+	}
+	c.JSON(http.StatusOK, gin.H{"data": feeds, "exportUrl": exportURL})
+}
+
+func (h *ICalHandler) CreateFeed(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+
+	var feed models.ICalFeed
+	if err := c.ShouldBindJSON(&feed); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+	feed.RoomID = uint(roomID)
+
+	if err := h.icalService.CreateFeed(&feed); err != nil {
+		respondICalError(c, err, "Failed to create iCal feed")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": feed})
+}
+
+func (h *ICalHandler) DeleteFeed(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid iCal feed ID"})
+		return
+	}
+
+	if err := h.icalService.DeleteFeed(uint(id)); err != nil {
+		respondICalError(c, err, "Failed to delete iCal feed")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "iCal feed deleted successfully"})
+}
+
+// SyncFeed imports a feed straight away instead of waiting for the scheduled sync
+func (h *ICalHandler) SyncFeed(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid iCal feed ID"})
+		return
+	}
+
+	result, err := h.icalService.SyncFeed(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "iCal feed not found"})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to import iCal feed", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "iCal feed imported successfully", "data": result})
+}
+
+func respondICalError(c *gin.Context, err error, fallback string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Room or iCal feed not found"})
+		return
+	}
+
+	var validationErr *services.BookingValidationError
+	if errors.As(err, &validationErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+}
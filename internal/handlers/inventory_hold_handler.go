@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+	"flyola-services/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type HoldHandler struct {
+	holdService *services.HoldService
+}
+
+func NewHoldHandler(holdService *services.HoldService) *HoldHandler {
+	return &HoldHandler{holdService: holdService}
+}
+
+// CreateHold keeps room nights or package seats aside while the guest pays. The returned token is
+// sent as hold_token when the booking is created.
+func (h *HoldHandler) CreateHold(c *gin.Context) {
+	var req services.HoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	hold, err := h.holdService.CreateHold(&req)
+	if err != nil {
+		respondHoldError(c, err, "Failed to hold inventory")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Inventory held successfully", "data": hold})
+}
+
+func (h *HoldHandler) GetHold(c *gin.Context) {
+	hold, err := h.holdService.GetHold(c.Param("token"))
+	if err != nil {
+		respondHoldError(c, err, "Failed to fetch hold")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Hold retrieved successfully", "data": hold})
+}
+
+// ReleaseHold gives held inventory back when the guest abandons checkout
+func (h *HoldHandler) ReleaseHold(c *gin.Context) {
+	if err := h.holdService.ReleaseHold(c.Param("token")); err != nil {
+		respondHoldError(c, err, "Failed to release hold")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Hold released successfully"})
+}
+
+func respondHoldError(c *gin.Context, err error, fallback string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Hold not found"})
+		return
+	}
+
+	var seatsErr *services.PackageSeatsError
+	if errors.As(err, &seatsErr) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":     "Not enough seats are left on the selected date",
+			"details":   seatsErr.Error(),
+			"available": seatsErr.Available,
+		})
+		return
+	}
+
+	if respondBookingError(c, err) {
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+}
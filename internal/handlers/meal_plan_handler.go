@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"errors"
 	"flyola-services/internal/models"
 	"flyola-services/internal/services"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type MealPlanHandler struct {
@@ -88,4 +91,117 @@ func (h *MealPlanHandler) DeleteMealPlan(c *gin.Context) {
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Meal plan deleted successfully"})
-}
\ No newline at end of file
+}
+
+func (h *MealPlanHandler) GetMealPlanRates(c *gin.Context) {
+	categoryID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	rates, err := h.mealPlanService.GetMealPlanRates(uint(categoryID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch meal plan rates"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": rates})
+}
+
+func (h *MealPlanHandler) CreateMealPlanRate(c *gin.Context) {
+	categoryID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	var rate models.MealPlanRate
+	if err := c.ShouldBindJSON(&rate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+	rate.RoomCategoryID = uint(categoryID)
+
+	if err := h.mealPlanService.CreateMealPlanRate(&rate); err != nil {
+		respondMealPlanRateError(c, err, "Failed to create meal plan rate")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": rate})
+}
+
+func (h *MealPlanHandler) UpdateMealPlanRate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid meal plan rate ID"})
+		return
+	}
+
+	var updates models.MealPlanRate
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	rate, err := h.mealPlanService.UpdateMealPlanRate(uint(id), &updates)
+	if err != nil {
+		respondMealPlanRateError(c, err, "Failed to update meal plan rate")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": rate})
+}
+
+func (h *MealPlanHandler) DeleteMealPlanRate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid meal plan rate ID"})
+		return
+	}
+
+	if err := h.mealPlanService.DeleteMealPlanRate(uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete meal plan rate"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Meal plan rate deleted successfully"})
+}
+
+// GetMealPlanOffers lists the meal plans bookable with a room category for a stay
+func (h *MealPlanHandler) GetMealPlanOffers(c *gin.Context) {
+	categoryID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	checkIn, err := time.ParseInLocation("2006-01-02", c.Query("check_in"), time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "check_in is required (YYYY-MM-DD)"})
+		return
+	}
+	checkOut, err := time.ParseInLocation("2006-01-02", c.Query("check_out"), time.Local)
+	if err != nil || !checkOut.After(checkIn) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "check_out is required (YYYY-MM-DD) and must be after check_in"})
+		return
+	}
+
+	offers, err := h.mealPlanService.GetMealPlanOffers(uint(categoryID), checkIn, checkOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch meal plan offers"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": offers})
+}
+
+func respondMealPlanRateError(c *gin.Context, err error, fallback string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Meal plan rate, meal plan or room category not found"})
+		return
+	}
+
+	var validationErr *services.BookingValidationError
+	if errors.As(err, &validationErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"errors"
+	"flyola-services/internal/models"
+	"flyola-services/internal/services"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type OrderHandler struct {
+	orderService   *services.OrderService
+	paymentService *services.PaymentService
+	razorpayID     string
+	razorpaySecret string
+}
+
+func NewOrderHandler(orderService *services.OrderService, paymentService *services.PaymentService, razorpayID, razorpaySecret string) *OrderHandler {
+	return &OrderHandler{
+		orderService:   orderService,
+		paymentService: paymentService,
+		razorpayID:     razorpayID,
+		razorpaySecret: razorpaySecret,
+	}
+}
+
+// CreateOrder books several rooms at once and creates the single Razorpay order that pays for them
+func (h *OrderHandler) CreateOrder(c *gin.Context) {
+	var order models.BookingOrder
+	if err := c.ShouldBindJSON(&order); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	if err := h.orderService.CreateOrder(&order, requestActor(c, "guest")); err != nil {
+		if respondOrderError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order", "details": err.Error()})
+		return
+	}
+
+	// The rooms stay reserved even if Razorpay is unavailable; the payment order can be created again later
+	if err := h.createPaymentOrder(&order); err != nil {
+		log.Printf("❌ Razorpay order creation failed for order %s: %v", order.OrderReference, err)
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "Order created but the payment order could not be created",
+			"details": err.Error(),
+			"data":    order,
+		})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Order created successfully", "data": order})
+}
+
+func (h *OrderHandler) GetOrderByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	order, err := h.orderService.GetOrderByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Order retrieved successfully", "data": order})
+}
+
+// CreatePaymentOrder creates a new Razorpay order for an unpaid order
+func (h *OrderHandler) CreatePaymentOrder(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	order, err := h.orderService.GetOrderByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
+	if err := h.createPaymentOrder(order); err != nil {
+		if respondOrderError(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to create Razorpay order", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Payment order created successfully", "data": order})
+}
+
+func (h *OrderHandler) createPaymentOrder(order *models.BookingOrder) error {
+	amount, err := h.orderService.PayableAmount(order)
+	if err != nil {
+		return err
+	}
+
+	razorpayOrderID, err := h.paymentService.CreateRazorpayOrder(amount, "INR", order.OrderReference, h.razorpayID, h.razorpaySecret)
+	if err != nil {
+		return err
+	}
+
+	if err := h.orderService.AttachPaymentOrder(order.ID, razorpayOrderID, amount); err != nil {
+		return err
+	}
+	order.RazorpayOrderID = razorpayOrderID
+	order.RazorpayOrderAmount = amount
+	return nil
+}
+
+// PreviewCancellation shows the refund for cancelling the rest of an order now
+func (h *OrderHandler) PreviewCancellation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	cancellation, err := h.orderService.PreviewCancellation(uint(id))
+	if err != nil {
+		if respondOrderError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute cancellation refund"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Cancellation preview computed successfully", "data": cancellation})
+}
+
+// CancelOrder cancels every room of an order that can still be cancelled
+func (h *OrderHandler) CancelOrder(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	cancellation, err := h.orderService.CancelOrder(uint(id), requestActor(c, "guest"), req.Reason)
+	if err != nil {
+		if respondOrderError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel order"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Order cancelled successfully", "data": cancellation})
+}
+
+// respondOrderError handles order-specific errors before falling back to the booking errors
+// reported for the rooms of the order
+func respondOrderError(c *gin.Context, err error) bool {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return true
+	}
+	if errors.Is(err, services.ErrPaymentOrderMismatch) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return true
+	}
+	return respondBookingError(c, err)
+}
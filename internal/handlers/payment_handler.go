@@ -4,6 +4,7 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"flyola-services/internal/models"
 	"flyola-services/internal/services"
 	"log"
@@ -11,31 +12,36 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type PaymentHandler struct {
 	paymentService *services.PaymentService
 	bookingService *services.BookingService
+	orderService   *services.OrderService
 	razorpayID     string
 	razorpaySecret string
 }
 
-func NewPaymentHandler(paymentService *services.PaymentService, bookingService *services.BookingService, razorpayID, razorpaySecret string) *PaymentHandler {
+func NewPaymentHandler(paymentService *services.PaymentService, bookingService *services.BookingService, orderService *services.OrderService, razorpayID, razorpaySecret string) *PaymentHandler {
 	return &PaymentHandler{
 		paymentService: paymentService,
 		bookingService: bookingService,
+		orderService:   orderService,
 		razorpayID:     razorpayID,
 		razorpaySecret: razorpaySecret,
 	}
 }
 
-// CreateOrder creates a new Razorpay order (for both hotel and package bookings)
+// CreateOrder creates a new Razorpay order (for both hotel and package bookings). Hotel bookings
+// pass booking_id and are charged their server-computed amount; other payments pass the amount.
 func (h *PaymentHandler) CreateOrder(c *gin.Context) {
 	var req struct {
-		Amount   interface{}            `json:"amount" binding:"required"`
-		Currency string                 `json:"currency" binding:"required"`
-		Receipt  string                 `json:"receipt" binding:"required"`
-		Notes    map[string]interface{} `json:"notes"`
+		BookingID *uint                  `json:"booking_id"`
+		Amount    interface{}            `json:"amount"`
+		Currency  string                 `json:"currency"`
+		Receipt   string                 `json:"receipt"`
+		Notes     map[string]interface{} `json:"notes"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -43,6 +49,15 @@ func (h *PaymentHandler) CreateOrder(c *gin.Context) {
 		return
 	}
 
+	if req.BookingID != nil {
+		h.createBookingOrder(c, *req.BookingID, req.Notes)
+		return
+	}
+	if req.Amount == nil || req.Currency == "" || req.Receipt == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "booking_id, or amount, currency and receipt are required"})
+		return
+	}
+
 	// Convert amount to int64
 	var amount int64
 	switch v := req.Amount.(type) {
@@ -85,10 +100,48 @@ func (h *PaymentHandler) CreateOrder(c *gin.Context) {
 	})
 }
 
+// createBookingOrder creates the Razorpay order for the final amount of an unpaid hotel booking and
+// stores it on the booking, so only a payment of that order confirms the booking
+func (h *PaymentHandler) createBookingOrder(c *gin.Context, bookingID uint, notes map[string]interface{}) {
+	booking, amount, err := h.bookingService.PayableBooking(bookingID)
+	if err != nil {
+		if !respondBookingError(c, err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create Razorpay order"})
+		}
+		return
+	}
+
+	orderID, err := h.paymentService.CreateRazorpayOrder(amount, "INR", booking.BookingReference, h.razorpayID, h.razorpaySecret)
+	if err != nil {
+		log.Printf("❌ Razorpay order creation failed for booking %s: %v", booking.BookingReference, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to create Razorpay order", "details": err.Error()})
+		return
+	}
+	if err := h.bookingService.AttachPaymentOrder(booking.ID, orderID, amount); err != nil {
+		if !respondBookingError(c, err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create Razorpay order"})
+		}
+		return
+	}
+
+	log.Printf("✅ Razorpay order %s created for booking %s", orderID, booking.BookingReference)
+	c.JSON(http.StatusOK, gin.H{
+		"id":         orderID,
+		"entity":     "order",
+		"amount":     amount,
+		"currency":   "INR",
+		"receipt":    booking.BookingReference,
+		"status":     "created",
+		"notes":      notes,
+		"booking_id": booking.ID,
+	})
+}
+
 // VerifyPayment verifies the Razorpay payment signature (for both hotel and package bookings)
 func (h *PaymentHandler) VerifyPayment(c *gin.Context) {
 	var req struct {
 		BookingID         *uint  `json:"booking_id"` // Optional for hotel bookings
+		OrderID           *uint  `json:"order_id"`   // Optional for multi-room orders
 		RazorpayOrderID   string `json:"razorpay_order_id" binding:"required"`
 		RazorpayPaymentID string `json:"razorpay_payment_id" binding:"required"`
 		RazorpaySignature string `json:"razorpay_signature" binding:"required"`
@@ -101,7 +154,7 @@ func (h *PaymentHandler) VerifyPayment(c *gin.Context) {
 
 	// Verify signature using HMAC SHA256
 	verified := h.verifyRazorpaySignature(req.RazorpayOrderID, req.RazorpayPaymentID, req.RazorpaySignature)
-	
+
 	if !verified {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success":  false,
@@ -111,17 +164,43 @@ func (h *PaymentHandler) VerifyPayment(c *gin.Context) {
 		return
 	}
 
-	// If booking_id is provided, update the hotel booking status
-	if req.BookingID != nil {
-		updates := &models.HotelBooking{
-			BookingStatus: "confirmed",
-			PaymentStatus: "paid",
-			PaymentID:     req.RazorpayPaymentID,
-			PaymentMethod: "razorpay",
+	// If order_id is provided, confirm every room booked in the order
+	if req.OrderID != nil {
+		order, err := h.orderService.ConfirmPayment(*req.OrderID, req.RazorpayOrderID, req.RazorpayPaymentID, "razorpay")
+		if err != nil {
+			if respondOrderError(c, err) {
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order status", "details": err.Error()})
+			return
 		}
 
-		booking, err := h.bookingService.UpdateBooking(*req.BookingID, updates)
+		c.JSON(http.StatusOK, gin.H{
+			"success":  true,
+			"verified": true,
+			"message":  "Payment verified and order confirmed",
+			"data":     order,
+		})
+		return
+	}
+
+	// If booking_id is provided, update the hotel booking status
+	if req.BookingID != nil {
+		booking, err := h.bookingService.ConfirmPayment(*req.BookingID, req.RazorpayOrderID, req.RazorpayPaymentID, "razorpay")
 		if err != nil {
+			var transitionErr *services.InvalidTransitionError
+			if errors.As(err, &transitionErr) {
+				c.JSON(http.StatusConflict, gin.H{"error": "Booking cannot be confirmed", "details": err.Error()})
+				return
+			}
+			if errors.Is(err, services.ErrBookingPaymentMismatch) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking status", "details": err.Error()})
 			return
 		}
@@ -147,7 +226,7 @@ func (h *PaymentHandler) VerifyPayment(c *gin.Context) {
 func (h *PaymentHandler) verifyRazorpaySignature(orderID, paymentID, signature string) bool {
 	// Create the expected signature
 	message := orderID + "|" + paymentID
-	
+
 	// Create HMAC SHA256 hash
 	hmacHash := hmac.New(sha256.New, []byte(h.razorpaySecret))
 	hmacHash.Write([]byte(message))
@@ -186,6 +265,49 @@ func (h *PaymentHandler) GetPaymentByID(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Payment retrieved successfully", "data": payment})
 }
 
+// DeletePayment soft deletes a payment (Admin only)
+func (h *PaymentHandler) DeletePayment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		return
+	}
+
+	if err := h.paymentService.DeletePayment(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete payment"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Payment deleted successfully"})
+}
+
+// RestorePayment brings back a deleted payment (Admin only)
+func (h *PaymentHandler) RestorePayment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		return
+	}
+
+	payment, err := h.paymentService.RestorePayment(uint(id))
+	if err != nil {
+		var validationErr *services.BookingValidationError
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		case errors.As(err, &validationErr):
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Message})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore payment"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Payment restored successfully", "data": payment})
+}
+
 func (h *PaymentHandler) GetPaymentByBooking(c *gin.Context) {
 	bookingID, err := strconv.ParseUint(c.Param("bookingId"), 10, 32)
 	if err != nil {
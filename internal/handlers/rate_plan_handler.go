@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"errors"
+	"flyola-services/internal/models"
+	"flyola-services/internal/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RatePlanHandler struct {
+	ratePlanService *services.RatePlanService
+}
+
+func NewRatePlanHandler(ratePlanService *services.RatePlanService) *RatePlanHandler {
+	return &RatePlanHandler{ratePlanService: ratePlanService}
+}
+
+func (h *RatePlanHandler) GetHotelRatePlans(c *gin.Context) {
+	hotelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hotel ID"})
+		return
+	}
+
+	plans, err := h.ratePlanService.GetHotelRatePlans(uint(hotelID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rate plans"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": plans})
+}
+
+func (h *RatePlanHandler) GetRatePlan(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rate plan ID"})
+		return
+	}
+
+	plan, err := h.ratePlanService.GetRatePlan(uint(id))
+	if err != nil {
+		respondRatePlanError(c, err, "Failed to fetch rate plan")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": plan})
+}
+
+func (h *RatePlanHandler) CreateRatePlan(c *gin.Context) {
+	hotelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hotel ID"})
+		return
+	}
+
+	var plan models.RatePlan
+	if err := c.ShouldBindJSON(&plan); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+	plan.HotelID = uint(hotelID)
+
+	if err := h.ratePlanService.CreateRatePlan(&plan); err != nil {
+		respondRatePlanError(c, err, "Failed to create rate plan")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": plan})
+}
+
+func (h *RatePlanHandler) UpdateRatePlan(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rate plan ID"})
+		return
+	}
+
+	var updates models.RatePlan
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	plan, err := h.ratePlanService.UpdateRatePlan(uint(id), &updates)
+	if err != nil {
+		respondRatePlanError(c, err, "Failed to update rate plan")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": plan})
+}
+
+func (h *RatePlanHandler) DeleteRatePlan(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rate plan ID"})
+		return
+	}
+
+	if err := h.ratePlanService.DeleteRatePlan(uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete rate plan"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Rate plan deleted successfully"})
+}
+
+// GetRoomRates shows the resolved nightly price of a room for ?from= up to ?to=
+func (h *RatePlanHandler) GetRoomRates(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+
+	from, err := time.ParseInLocation("2006-01-02", c.Query("from"), time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from is required (YYYY-MM-DD)"})
+		return
+	}
+	to, err := time.ParseInLocation("2006-01-02", c.Query("to"), time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to is required (YYYY-MM-DD)"})
+		return
+	}
+	guests, err := strconv.Atoi(c.DefaultQuery("guests", "2"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid guests"})
+		return
+	}
+
+	rates, err := h.ratePlanService.GetRoomRates(uint(roomID), from, to, guests)
+	if err != nil {
+		respondRatePlanError(c, err, "Failed to resolve room rates")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": rates})
+}
+
+func respondRatePlanError(c *gin.Context, err error, fallback string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rate plan, hotel, room or room category not found"})
+		return
+	}
+
+	var validationErr *services.BookingValidationError
+	if errors.As(err, &validationErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+}
@@ -1,20 +1,23 @@
 package handlers
 
 import (
+	"errors"
 	"flyola-services/internal/models"
 	"flyola-services/internal/services"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type RoomAvailabilityHandler struct {
 	roomAvailabilityService *services.RoomAvailabilityService
+	horizonDays             int
 }
 
-func NewRoomAvailabilityHandler(roomAvailabilityService *services.RoomAvailabilityService) *RoomAvailabilityHandler {
-	return &RoomAvailabilityHandler{roomAvailabilityService: roomAvailabilityService}
+func NewRoomAvailabilityHandler(roomAvailabilityService *services.RoomAvailabilityService, horizonDays int) *RoomAvailabilityHandler {
+	return &RoomAvailabilityHandler{roomAvailabilityService: roomAvailabilityService, horizonDays: horizonDays}
 }
 
 func (h *RoomAvailabilityHandler) GetRoomAvailability(c *gin.Context) {
@@ -126,4 +129,128 @@ func (h *RoomAvailabilityHandler) DeleteRoomAvailability(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Room availability deleted successfully",
 	})
+}
+
+// GenerateCalendar fills in a hotel's availability calendar up to the configured horizon,
+// or up to ?days= ahead
+func (h *RoomAvailabilityHandler) GenerateCalendar(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid hotel ID",
+		})
+		return
+	}
+
+	days := h.horizonDays
+	if value := c.Query("days"); value != "" {
+		days, err = strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid days",
+			})
+			return
+		}
+	}
+
+	hotelID := uint(id)
+	result, err := h.roomAvailabilityService.GenerateCalendar(&hotelID, days)
+	if err != nil {
+		var validationErr *services.BookingValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid calendar request",
+				"details": validationErr.Error(),
+			})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Hotel not found",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to generate availability calendar",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Availability calendar generated successfully",
+		"data":    result,
+	})
+}
+
+// BulkUpdateAvailability sets availability and/or price for many rooms and nights at once,
+// creating the nights that have no row yet
+func (h *RoomAvailabilityHandler) BulkUpdateAvailability(c *gin.Context) {
+	var update services.BulkAvailabilityUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	result, err := h.roomAvailabilityService.BulkUpdateAvailability(&update)
+	if err != nil {
+		var validationErr *services.BookingValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid availability update",
+				"details": validationErr.Error(),
+			})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Room category not found",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to update room availability",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Room availability updated successfully",
+		"data":    result,
+	})
+}
+
+// GetAvailabilityMatrix returns the room by date grid of a hotel from ?from= to ?to= (inclusive)
+func (h *RoomAvailabilityHandler) GetAvailabilityMatrix(c *gin.Context) {
+	hotelID, from, to, ok := hotelDateRange(c)
+	if !ok {
+		return
+	}
+
+	matrix, err := h.roomAvailabilityService.GetAvailabilityMatrix(hotelID, from, to)
+	if err != nil {
+		var validationErr *services.BookingValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid date range",
+				"details": validationErr.Error(),
+			})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Hotel not found",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to fetch availability matrix",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": matrix,
+	})
 }
\ No newline at end of file
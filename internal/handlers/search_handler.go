@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"flyola-services/internal/services"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SearchHandler struct {
+	searchService *services.SearchService
+}
+
+func NewSearchHandler(searchService *services.SearchService) *SearchHandler {
+	return &SearchHandler{searchService: searchService}
+}
+
+// SearchHotels lists the hotels of a city with a room free for the whole stay and party
+func (h *SearchHandler) SearchHotels(c *gin.Context) {
+	cityID, err := strconv.ParseUint(c.Query("city_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "city_id is required"})
+		return
+	}
+
+	checkIn, err := time.ParseInLocation("2006-01-02", c.Query("check_in"), time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "check_in is required (YYYY-MM-DD)"})
+		return
+	}
+	checkOut, err := time.ParseInLocation("2006-01-02", c.Query("check_out"), time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "check_out is required (YYYY-MM-DD)"})
+		return
+	}
+
+	adults, err := strconv.Atoi(c.DefaultQuery("adults", "1"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid adults"})
+		return
+	}
+	children, err := strconv.Atoi(c.DefaultQuery("children", "0"))
+	if err != nil || children < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid children"})
+		return
+	}
+
+	search := &services.HotelSearch{
+		CityID:   uint(cityID),
+		CheckIn:  checkIn,
+		CheckOut: checkOut,
+		Adults:   adults,
+		Children: children,
+	}
+	for _, rating := range splitQueryList(c.Query("star_rating")) {
+		stars, err := strconv.Atoi(rating)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid star_rating"})
+			return
+		}
+		search.StarRatings = append(search.StarRatings, stars)
+	}
+	search.Amenities = splitQueryList(c.Query("amenities"))
+	for _, value := range splitQueryList(c.Query("child_ages")) {
+		age, err := strconv.Atoi(value)
+		if err != nil || age < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid child_ages"})
+			return
+		}
+		search.ChildAges = append(search.ChildAges, age)
+	}
+
+	results, err := h.searchService.SearchHotels(search)
+	if err != nil {
+		var validationErr *services.BookingValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Message})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search hotels"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Hotels retrieved successfully", "data": results})
+}
+
+// splitQueryList splits a comma-separated query parameter, dropping empty entries
+func splitQueryList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"flyola-services/internal/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type StayRestrictionHandler struct {
+	restrictionService *services.StayRestrictionService
+}
+
+func NewStayRestrictionHandler(restrictionService *services.StayRestrictionService) *StayRestrictionHandler {
+	return &StayRestrictionHandler{restrictionService: restrictionService}
+}
+
+// GetHotelRestrictions lists a hotel's stay restrictions from ?from= to ?to=, optionally for one
+// ?room_category_id=
+func (h *StayRestrictionHandler) GetHotelRestrictions(c *gin.Context) {
+	hotelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hotel ID"})
+		return
+	}
+
+	from, err := time.ParseInLocation("2006-01-02", c.Query("from"), time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from is required (YYYY-MM-DD)"})
+		return
+	}
+	to, err := time.ParseInLocation("2006-01-02", c.Query("to"), time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to is required (YYYY-MM-DD)"})
+		return
+	}
+
+	var roomCategoryID *uint
+	if value := c.Query("room_category_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room_category_id"})
+			return
+		}
+		categoryID := uint(id)
+		roomCategoryID = &categoryID
+	}
+
+	restrictions, err := h.restrictionService.GetRestrictions(uint(hotelID), roomCategoryID, from, to)
+	if err != nil {
+		respondRestrictionError(c, err, "Failed to fetch stay restrictions")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": restrictions})
+}
+
+func (h *StayRestrictionHandler) SetHotelRestrictions(c *gin.Context) {
+	hotelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hotel ID"})
+		return
+	}
+
+	var update services.StayRestrictionUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	dates, err := h.restrictionService.SetRestrictions(uint(hotelID), &update)
+	if err != nil {
+		respondRestrictionError(c, err, "Failed to update stay restrictions")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Stay restrictions updated successfully", "data": gin.H{"dates": dates}})
+}
+
+func (h *StayRestrictionHandler) DeleteRestriction(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stay restriction ID"})
+		return
+	}
+
+	if err := h.restrictionService.DeleteRestriction(uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete stay restriction"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Stay restriction deleted successfully"})
+}
+
+func respondRestrictionError(c *gin.Context, err error, fallback string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Hotel or room category not found"})
+		return
+	}
+
+	var validationErr *services.BookingValidationError
+	if errors.As(err, &validationErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+}
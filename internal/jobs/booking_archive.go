@@ -0,0 +1,30 @@
+package jobs
+
+import (
+	"context"
+	"flyola-services/internal/services"
+	"log"
+)
+
+// archiveBatchSize is how many bookings are archived per transaction
+const archiveBatchSize = 100
+
+// archiveOldBookings moves hotel bookings past the retention window into the archive tables,
+// working in batches until none are left
+func archiveOldBookings(ctx context.Context, bookingService *services.BookingService, retentionDays int) error {
+	archived := 0
+	for ctx.Err() == nil {
+		count, err := bookingService.ArchiveBookings(retentionDays, archiveBatchSize)
+		if err != nil {
+			return err
+		}
+		archived += count
+		if count < archiveBatchSize {
+			break
+		}
+	}
+	if archived > 0 {
+		log.Printf("🗄️  Archived %d hotel booking(s) older than %d days", archived, retentionDays)
+	}
+	return nil
+}
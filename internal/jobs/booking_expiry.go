@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"context"
+	"flyola-services/internal/services"
+	"log"
+	"time"
+)
+
+// expiryBatchSize is how many bookings are expired per transaction
+const expiryBatchSize = 50
+
+// expirePendingBookings expires hotel and package bookings left unpaid for longer than ttl,
+// working in batches until none are left
+func expirePendingBookings(ctx context.Context, bookingService *services.BookingService, holidayPackageService *services.HolidayPackageService, ttl time.Duration) error {
+	for ctx.Err() == nil {
+		expired, err := bookingService.ExpirePendingBookings(ttl, expiryBatchSize)
+		if err != nil {
+			return err
+		}
+		if expired > 0 {
+			log.Printf("⌛ Expired %d unpaid hotel booking(s)", expired)
+		}
+		if expired < expiryBatchSize {
+			break
+		}
+	}
+
+	for ctx.Err() == nil {
+		expired, err := holidayPackageService.ExpirePendingBookings(ttl, expiryBatchSize)
+		if err != nil {
+			return err
+		}
+		if expired > 0 {
+			log.Printf("⌛ Expired %d unpaid package booking(s)", expired)
+		}
+		if expired < expiryBatchSize {
+			break
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,15 @@
+package jobs
+
+import (
+	"flyola-services/internal/services"
+	"log"
+)
+
+// applyDynamicPricing reprices the free room nights of every hotel with dynamic pricing enabled
+func applyDynamicPricing(dynamicPricingService *services.DynamicPricingService) error {
+	changed, err := dynamicPricingService.ApplyAll()
+	if changed > 0 {
+		log.Printf("📈 Dynamic pricing: %d room night(s) repriced", changed)
+	}
+	return err
+}
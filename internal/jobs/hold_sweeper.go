@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"context"
+	"flyola-services/internal/services"
+	"log"
+)
+
+// holdSweepBatchSize is how many holds are expired per transaction
+const holdSweepBatchSize = 100
+
+// sweepExpiredHolds gives back the inventory of checkout holds whose time is up, working in
+// batches until none are left
+func sweepExpiredHolds(ctx context.Context, holdService *services.HoldService) error {
+	for ctx.Err() == nil {
+		expired, err := holdService.ExpireHolds(holdSweepBatchSize)
+		if err != nil {
+			return err
+		}
+		if expired > 0 {
+			log.Printf("⌛ Released %d expired inventory hold(s)", expired)
+		}
+		if expired < holdSweepBatchSize {
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package jobs
+
+import (
+	"context"
+	"flyola-services/internal/services"
+	"log"
+)
+
+// idempotencyPurgeBatchSize is how many idempotency keys are deleted per statement
+const idempotencyPurgeBatchSize = 500
+
+// purgeIdempotencyKeys deletes idempotency keys past their retention, working in batches until
+// none are left
+func purgeIdempotencyKeys(ctx context.Context, idempotencyService *services.IdempotencyService) error {
+	purged := 0
+	for ctx.Err() == nil {
+		deleted, err := idempotencyService.PurgeExpired(idempotencyPurgeBatchSize)
+		if err != nil {
+			return err
+		}
+		purged += deleted
+		if deleted < idempotencyPurgeBatchSize {
+			break
+		}
+	}
+	if purged > 0 {
+		log.Printf("🧹 Purged %d expired idempotency key(s)", purged)
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package jobs
+
+import (
+	"flyola-services/internal/services"
+	"log"
+)
+
+// generateRoomCalendar keeps horizonDays of availability rows ahead for every active room
+func generateRoomCalendar(roomAvailabilityService *services.RoomAvailabilityService, horizonDays int) error {
+	result, err := roomAvailabilityService.GenerateCalendar(nil, horizonDays)
+	if err != nil {
+		return err
+	}
+	if result.Created > 0 || result.Repriced > 0 {
+		log.Printf("📅 Room calendar: %d night(s) created, %d repriced across %d room(s)", result.Created, result.Repriced, result.Rooms)
+	}
+	return nil
+}
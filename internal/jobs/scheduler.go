@@ -0,0 +1,71 @@
+package jobs
+
+import (
+	"context"
+	"flyola-services/internal/config"
+	"flyola-services/internal/services"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Start launches the in-process background jobs. They stop when ctx is cancelled.
+func Start(ctx context.Context, db *gorm.DB, cfg *config.Config) {
+	bookingService := services.NewBookingService(db, cfg.HotelTaxPercent)
+	holidayPackageService := services.NewHolidayPackageService(db, cfg.NodeBackendURL)
+	roomAvailabilityService := services.NewRoomAvailabilityService(db)
+	dynamicPricingService := services.NewDynamicPricingService(db)
+	icalService := services.NewICalService(db, cfg.ICalFeedSecret, cfg.AvailabilityHorizonDays)
+	holdService := services.NewHoldService(db, cfg.InventoryHoldTTL)
+	idempotencyService := services.NewIdempotencyService(db, cfg.IdempotencyKeyTTL)
+
+	every(ctx, "booking-expiry", cfg.BookingExpiryInterval, func(ctx context.Context) error {
+		return expirePendingBookings(ctx, bookingService, holidayPackageService, cfg.PendingBookingTTL)
+	})
+	every(ctx, "room-calendar", cfg.CalendarGenerationInterval, func(ctx context.Context) error {
+		return generateRoomCalendar(roomAvailabilityService, cfg.AvailabilityHorizonDays)
+	})
+	every(ctx, "dynamic-pricing", cfg.DynamicPricingInterval, func(ctx context.Context) error {
+		return applyDynamicPricing(dynamicPricingService)
+	})
+	every(ctx, "ical-sync", cfg.ICalSyncInterval, func(ctx context.Context) error {
+		return icalService.SyncAll(ctx)
+	})
+	every(ctx, "hold-sweeper", cfg.HoldSweepInterval, func(ctx context.Context) error {
+		return sweepExpiredHolds(ctx, holdService)
+	})
+	every(ctx, "idempotency-purge", cfg.IdempotencyPurgeInterval, func(ctx context.Context) error {
+		return purgeIdempotencyKeys(ctx, idempotencyService)
+	})
+	every(ctx, "booking-archive", cfg.BookingArchiveInterval, func(ctx context.Context) error {
+		return archiveOldBookings(ctx, bookingService, cfg.BookingRetentionDays)
+	})
+}
+
+// every runs job straight away and then once per interval until ctx is cancelled.
+// A non-positive interval disables the job.
+func every(ctx context.Context, name string, interval time.Duration, job func(ctx context.Context) error) {
+	if interval <= 0 {
+		log.Printf("⏸️  Job %s is disabled", name)
+		return
+	}
+
+	log.Printf("⏱️  Job %s scheduled every %s", name, interval)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := job(ctx); err != nil {
+				log.Printf("⚠️  Job %s failed: %v", name, err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminKeyHeader carries the admin API key on admin-only requests
+const AdminKeyHeader = "X-Admin-Key"
+
+// AdminOnly lets a request through only with the admin API key in the X-Admin-Key header.
+// Without a configured key every admin-only request is refused.
+func AdminOnly(apiKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if apiKey == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin access is not configured"})
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader(AdminKeyHeader)), []byte(apiKey)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid admin key"})
+			return
+		}
+		c.Next()
+	}
+}
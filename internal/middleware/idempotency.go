@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flyola-services/internal/services"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader is the header clients send to make a request safe to retry
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength matches the size of the stored key
+const maxIdempotencyKeyLength = 255
+
+// responseRecorder keeps a copy of everything written to the client
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency replays the stored response when a request is retried with the same Idempotency-Key.
+// Keys are scoped to the caller's Authorization header, so one caller can never be replayed another
+// caller's response. The key is bound to the method, path and body of its first request: reusing it
+// for a different request gets 422, and retrying while the first request is still running gets
+// 409. Responses with a server error are not stored, so the retry runs again. Requests without the
+// header are untouched.
+func Idempotency(idempotencyService *services.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body", "details": err.Error()})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(c.Request.URL.RawQuery + "\n"))
+		hash.Write(body)
+		fingerprint := hex.EncodeToString(hash.Sum(nil))
+		scope := c.Request.Method + " " + c.Request.URL.Path + " " + requestSubject(c)
+
+		record, replay, err := idempotencyService.Begin(key, scope, fingerprint)
+		if err != nil {
+			var mismatchErr *services.IdempotencyMismatchError
+			var inProgressErr *services.IdempotencyInProgressError
+			switch {
+			case errors.As(err, &mismatchErr):
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key reused with a different request", "details": err.Error()})
+			case errors.As(err, &inProgressErr):
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Request is still being processed", "details": err.Error()})
+			default:
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check Idempotency-Key", "details": err.Error()})
+			}
+			return
+		}
+		if replay {
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(record.StatusCode, record.ContentType, record.Response)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		// A panicking handler must not leave the key stuck in processing
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := idempotencyService.Release(record); err != nil {
+				log.Printf("⚠️  Failed to release idempotency key %s: %v", key, err)
+			}
+		}()
+
+		c.Next()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+		if err := idempotencyService.Complete(record, status, recorder.Header().Get("Content-Type"), recorder.body.Bytes()); err != nil {
+			log.Printf("⚠️  Failed to store response for idempotency key %s: %v", key, err)
+			return
+		}
+		completed = true
+	}
+}
+
+// requestSubject identifies the caller of a request by a hash of its Authorization header, so the
+// credentials themselves are never stored. Requests without one share the anonymous subject.
+func requestSubject(c *gin.Context) string {
+	authorization := c.GetHeader("Authorization")
+	if authorization == "" {
+		return "anonymous"
+	}
+	sum := sha256.Sum256([]byte(authorization))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,16 +1,25 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
 
 // HotelBooking represents a hotel booking
 type HotelBooking struct {
 	ID               uint      `json:"id" gorm:"primaryKey"`
 	BookingReference string    `json:"booking_reference" gorm:"unique;not null"`
 	UserID           *uint     `json:"user_id"`
+	OrderID          *uint     `json:"order_id" gorm:"index"`
 	HotelID          uint      `json:"hotel_id"`
 	Hotel            Hotel     `json:"hotel" gorm:"foreignKey:HotelID"`
-	RoomID           uint      `json:"room_id"`
-	Room             Room      `json:"room" gorm:"foreignKey:RoomID"`
+	RoomID           *uint     `json:"room_id"`
+	Room             *Room     `json:"room,omitempty" gorm:"foreignKey:RoomID"`
+	RoomCategoryID   *uint     `json:"room_category_id" gorm:"index"`
+	InventoryType    string    `json:"inventory_type" gorm:"default:room"`
 	GuestName        string    `json:"guest_name" gorm:"not null"`
 	GuestEmail       string    `json:"guest_email" gorm:"not null"`
 	GuestPhone       string    `json:"guest_phone" gorm:"not null"`
@@ -24,27 +33,149 @@ type HotelBooking struct {
 	TotalAmount      float64   `json:"total_amount"`
 	TaxAmount        float64   `json:"tax_amount" gorm:"default:0"`
 	DiscountAmount   float64   `json:"discount_amount" gorm:"default:0"`
+	MealPlanID       *uint     `json:"meal_plan_id"`
+	MealPlanAmount   float64   `json:"meal_plan_amount" gorm:"default:0"`
+	ChildAmount      float64   `json:"child_amount" gorm:"default:0"`
 	FinalAmount      float64   `json:"final_amount"`
 	BookingStatus    string    `json:"booking_status" gorm:"default:pending"`
 	PaymentStatus    string    `json:"payment_status" gorm:"default:pending"`
 	SpecialRequests  string    `json:"special_requests"`
 	PaymentID        string    `json:"payment_id" gorm:"column:payment_id"`
 	PaymentMethod    string    `json:"payment_method" gorm:"column:payment_method"`
-	BookingDate      time.Time `json:"booking_date" gorm:"default:CURRENT_TIMESTAMP"`
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
+	// Razorpay order created server-side to pay the booking, and its amount in paise
+	RazorpayOrderID     string `json:"razorpay_order_id" gorm:"size:64;index"`
+	RazorpayOrderAmount int64  `json:"razorpay_order_amount" gorm:"default:0"`
+	// Front desk: actual arrival and departure, and the incidentals on the folio closed at check-out
+	CheckedInAt       *time.Time `json:"checked_in_at"`
+	CheckedOutAt      *time.Time `json:"checked_out_at"`
+	IncidentalsAmount float64    `json:"incidentals_amount" gorm:"default:0"`
+	FolioClosedAt     *time.Time `json:"folio_closed_at"`
+	// Ages of the guests priced as children under the hotel's child policy
+	ChildAges datatypes.JSON `json:"child_ages"`
+	// Cancellation rules of the hotel at the time of booking
+	CancellationRules datatypes.JSON `json:"cancellation_rules"`
+	BookingDate       time.Time      `json:"booking_date" gorm:"default:CURRENT_TIMESTAMP"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+
+	// Associations
+	Guests []HotelGuest `json:"guests,omitempty" gorm:"foreignKey:BookingID"`
+
+	// Token of the checkout hold a new booking takes its room nights from; not stored
+	HoldToken string `json:"hold_token,omitempty" gorm:"-"`
+}
+
+// BeforeCreate hook to generate the booking reference
+func (b *HotelBooking) BeforeCreate(tx *gorm.DB) error {
+	if b.BookingReference == "" {
+		b.BookingReference = generateHotelBookingReference()
+	}
+	return nil
+}
+
+func (b *HotelBooking) GetChildAges() []int {
+	var ages []int
+	if b.ChildAges != nil {
+		json.Unmarshal(b.ChildAges, &ages)
+	}
+	return ages
+}
+
+func (b *HotelBooking) SetChildAges(ages []int) error {
+	data, err := json.Marshal(ages)
+	if err != nil {
+		return err
+	}
+	b.ChildAges = data
+	return nil
+}
+
+func generateHotelBookingReference() string {
+	// Generate unique booking reference (HTL + date + random)
+	return "HTL" + time.Now().Format("060102") + generateRandomString(6)
 }
 
 // HotelGuest represents guests in a booking
 type HotelGuest struct {
-	ID          uint         `json:"id" gorm:"primaryKey"`
-	BookingID   uint         `json:"booking_id"`
-	Booking     HotelBooking `json:"booking" gorm:"foreignKey:BookingID"`
-	Name        string       `json:"name" gorm:"not null"`
-	Age         *int         `json:"age"`
-	Gender      string       `json:"gender"`
-	IDType      string       `json:"id_type"`
-	IDNumber    string       `json:"id_number"`
-	IsMainGuest bool         `json:"is_main_guest" gorm:"default:false"`
-	CreatedAt   time.Time    `json:"created_at"`
+	ID          uint          `json:"id" gorm:"primaryKey"`
+	BookingID   uint          `json:"booking_id"`
+	Booking     *HotelBooking `json:"booking,omitempty" gorm:"foreignKey:BookingID"`
+	Name        string        `json:"name" gorm:"not null"`
+	Age         *int          `json:"age"`
+	Gender      string        `json:"gender"`
+	IDType      string        `json:"id_type"`
+	IDNumber    string        `json:"id_number"`
+	IsMainGuest bool          `json:"is_main_guest" gorm:"default:false"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+// Inventory a hotel booking is sold from. Room bookings hold a specific room; allotment bookings
+// take one room of a category's allotment and get their room assigned later, at the latest at check-in.
+const (
+	InventoryTypeRoom      = "room"
+	InventoryTypeAllotment = "allotment"
+)
+
+// Hotel booking lifecycle statuses
+const (
+	BookingStatusPending    = "pending"
+	BookingStatusConfirmed  = "confirmed"
+	BookingStatusCheckedIn  = "checked_in"
+	BookingStatusCheckedOut = "checked_out"
+	BookingStatusCancelled  = "cancelled"
+	BookingStatusNoShow     = "no_show"
+	BookingStatusExpired    = "expired"
+)
+
+// Hotel booking payment statuses
+const (
+	PaymentStatusPending  = "pending"
+	PaymentStatusPaid     = "paid"
+	PaymentStatusFailed   = "failed"
+	PaymentStatusRefunded = "refunded"
+)
+
+// bookingStatusTransitions lists the statuses each hotel booking status may move to.
+// checked_out, cancelled, no_show and expired are terminal.
+var bookingStatusTransitions = map[string][]string{
+	BookingStatusPending:   {BookingStatusConfirmed, BookingStatusCancelled, BookingStatusExpired},
+	BookingStatusConfirmed: {BookingStatusCheckedIn, BookingStatusCancelled, BookingStatusNoShow},
+	BookingStatusCheckedIn: {BookingStatusCheckedOut},
+}
+
+// CanTransitionBookingStatus reports whether a hotel booking may move from one status to another
+func CanTransitionBookingStatus(from, to string) bool {
+	for _, allowed := range bookingStatusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBookingStatus reports whether status is a known hotel booking status
+func IsBookingStatus(status string) bool {
+	switch status {
+	case BookingStatusPending, BookingStatusConfirmed, BookingStatusCheckedIn, BookingStatusCheckedOut,
+		BookingStatusCancelled, BookingStatusNoShow, BookingStatusExpired:
+		return true
+	}
+	return false
+}
+
+// BookingStatusHistory records a single hotel booking status transition
+type BookingStatusHistory struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	BookingID     uint      `json:"booking_id" gorm:"not null;index"`
+	FromStatus    string    `json:"from_status" gorm:"size:20"`
+	ToStatus      string    `json:"to_status" gorm:"size:20;not null"`
+	PaymentStatus string    `json:"payment_status" gorm:"size:20"`
+	Actor         string    `json:"actor" gorm:"size:100;not null"`
+	Reason        string    `json:"reason" gorm:"type:text"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func (BookingStatusHistory) TableName() string {
+	return "booking_status_history"
 }
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// BookingOrder groups several hotel room bookings made in a single checkout. The rooms are
+// reserved together and paid with one Razorpay order, but each booking keeps its own lifecycle,
+// so rooms can still be cancelled one at a time.
+type BookingOrder struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	OrderReference  string    `json:"order_reference" gorm:"uniqueIndex;size:20;not null"`
+	UserID          *uint     `json:"user_id"`
+	GuestName       string    `json:"guest_name" gorm:"not null"`
+	GuestEmail      string    `json:"guest_email" gorm:"not null"`
+	GuestPhone      string    `json:"guest_phone" gorm:"not null;size:20"`
+	TotalAmount     float64   `json:"total_amount"`
+	TaxAmount       float64   `json:"tax_amount"`
+	FinalAmount     float64   `json:"final_amount"`
+	PaymentStatus   string    `json:"payment_status" gorm:"size:20;default:pending"`
+	RazorpayOrderID string    `json:"razorpay_order_id" gorm:"size:64;index"`
+	PaymentID       string    `json:"payment_id"`
+	PaymentMethod   string    `json:"payment_method"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	// Amount in paise the Razorpay order was created for, checked when its payment is verified
+	RazorpayOrderAmount int64 `json:"razorpay_order_amount" gorm:"default:0"`
+
+	// Associations
+	Bookings []HotelBooking `json:"bookings,omitempty" gorm:"foreignKey:OrderID"`
+}
+
+func (BookingOrder) TableName() string {
+	return "booking_orders"
+}
+
+// BeforeCreate hook to generate the order reference
+func (o *BookingOrder) BeforeCreate(tx *gorm.DB) error {
+	if o.OrderReference == "" {
+		o.OrderReference = "ORD" + time.Now().Format("060102") + generateRandomString(6)
+	}
+	return nil
+}
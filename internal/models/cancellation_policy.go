@@ -0,0 +1,55 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// CancellationPolicy holds the refund rules of a hotel or a holiday package. Exactly one of
+// HotelID and PackageID is set. Bookings copy the rules in force when they are made.
+type CancellationPolicy struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	HotelID   *uint          `json:"hotel_id" gorm:"uniqueIndex"`
+	PackageID *uint          `json:"package_id" gorm:"uniqueIndex"`
+	Rules     datatypes.JSON `json:"rules" gorm:"not null;comment:Array of {hours_before, refund_percent}"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// CancellationRule refunds RefundPercent of the amount paid when a booking is cancelled at least
+// HoursBefore hours before check-in or travel
+type CancellationRule struct {
+	HoursBefore   int     `json:"hours_before"`
+	RefundPercent float64 `json:"refund_percent"`
+}
+
+// DefaultCancellationRules apply to hotels and packages without a policy of their own:
+// free cancellation until 48 hours before, 50% until 24 hours before, nothing after that
+var DefaultCancellationRules = []CancellationRule{
+	{HoursBefore: 48, RefundPercent: 100},
+	{HoursBefore: 24, RefundPercent: 50},
+	{HoursBefore: 0, RefundPercent: 0},
+}
+
+func (CancellationPolicy) TableName() string {
+	return "cancellation_policies"
+}
+
+func (p *CancellationPolicy) GetRules() []CancellationRule {
+	var rules []CancellationRule
+	if p.Rules != nil {
+		json.Unmarshal(p.Rules, &rules)
+	}
+	return rules
+}
+
+func (p *CancellationPolicy) SetRules(rules []CancellationRule) error {
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+	p.Rules = data
+	return nil
+}
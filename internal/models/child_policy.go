@@ -0,0 +1,50 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// ChildPolicy prices the children staying in a hotel's rooms by age band. Guests older than every
+// band are adults; hotels without a policy treat every guest as an adult.
+type ChildPolicy struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	HotelID   uint           `json:"hotelId" gorm:"column:hotel_id;not null;uniqueIndex"`
+	Bands     datatypes.JSON `json:"bands" gorm:"type:json;not null;comment:Array of {name, minAge, maxAge, nightlyPrice, countsTowardOccupancy}"`
+	CreatedAt time.Time      `json:"createdAt" gorm:"column:created_at"`
+	UpdatedAt time.Time      `json:"updatedAt" gorm:"column:updated_at"`
+}
+
+// ChildAgeBand charges NightlyPrice per night (0 for free) for each child aged MinAge to MaxAge,
+// both inclusive. Children in a band that CountsTowardOccupancy count against the room category's
+// maximum occupancy; others, such as infants in a cot, do not.
+type ChildAgeBand struct {
+	Name                  string  `json:"name"`
+	MinAge                int     `json:"minAge"`
+	MaxAge                int     `json:"maxAge"`
+	NightlyPrice          float64 `json:"nightlyPrice"`
+	CountsTowardOccupancy bool    `json:"countsTowardOccupancy"`
+}
+
+func (ChildPolicy) TableName() string {
+	return "child_policies"
+}
+
+func (p *ChildPolicy) GetBands() []ChildAgeBand {
+	var bands []ChildAgeBand
+	if p.Bands != nil {
+		json.Unmarshal(p.Bands, &bands)
+	}
+	return bands
+}
+
+func (p *ChildPolicy) SetBands(bands []ChildAgeBand) error {
+	data, err := json.Marshal(bands)
+	if err != nil {
+		return err
+	}
+	p.Bands = data
+	return nil
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// DynamicPricingRule moves the nightly price of a hotel's rooms up or down with the share of rooms
+// booked in their category that night. A rule with RoomCategoryID set applies to that category
+// and takes precedence over the hotel-wide rule (RoomCategoryID null).
+type DynamicPricingRule struct {
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	HotelID        uint           `json:"hotelId" gorm:"column:hotel_id;not null;index"`
+	RoomCategoryID *uint          `json:"roomCategoryId" gorm:"column:room_category_id"` // Optional: null for every category
+	Enabled        bool           `json:"enabled" gorm:"default:false"`
+	Bands          datatypes.JSON `json:"bands" gorm:"type:json;not null;comment:Array of {minOccupancy, adjustPercent}"`
+	FloorPrice     float64        `json:"floorPrice" gorm:"column:floor_price;type:decimal(10,2);default:0"`     // 0 for no floor
+	CeilingPrice   float64        `json:"ceilingPrice" gorm:"column:ceiling_price;type:decimal(10,2);default:0"` // 0 for no ceiling
+	LookaheadDays  int            `json:"lookaheadDays" gorm:"column:lookahead_days;default:90"`
+	CreatedAt      time.Time      `json:"createdAt" gorm:"column:created_at"`
+	UpdatedAt      time.Time      `json:"updatedAt" gorm:"column:updated_at"`
+}
+
+// OccupancyBand adjusts the price by AdjustPercent once at least MinOccupancy percent of the
+// category's rooms are booked for the night. The band with the highest MinOccupancy reached applies.
+type OccupancyBand struct {
+	MinOccupancy  float64 `json:"minOccupancy"`
+	AdjustPercent float64 `json:"adjustPercent"`
+}
+
+// DynamicPriceChange records a price set on a room night by dynamic pricing
+type DynamicPriceChange struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	RuleID         uint      `json:"ruleId" gorm:"column:rule_id;not null"`
+	HotelID        uint      `json:"hotelId" gorm:"column:hotel_id;not null;index:idx_hotel_date"`
+	RoomCategoryID uint      `json:"roomCategoryId" gorm:"column:room_category_id;not null"`
+	RoomID         uint      `json:"roomId" gorm:"column:room_id;not null"`
+	Date           time.Time `json:"date" gorm:"type:date;not null;index:idx_hotel_date"`
+	Occupancy      float64   `json:"occupancy" gorm:"type:decimal(5,2)"` // Percent of the category's rooms booked
+	AdjustPercent  float64   `json:"adjustPercent" gorm:"column:adjust_percent;type:decimal(6,2)"`
+	OldPrice       float64   `json:"oldPrice" gorm:"column:old_price;type:decimal(10,2)"`
+	NewPrice       float64   `json:"newPrice" gorm:"column:new_price;type:decimal(10,2)"`
+	CreatedAt      time.Time `json:"createdAt" gorm:"column:created_at"`
+}
+
+func (DynamicPricingRule) TableName() string {
+	return "dynamic_pricing_rules"
+}
+
+func (DynamicPriceChange) TableName() string {
+	return "dynamic_price_changes"
+}
+
+func (r *DynamicPricingRule) GetBands() []OccupancyBand {
+	var bands []OccupancyBand
+	if r.Bands != nil {
+		json.Unmarshal(r.Bands, &bands)
+	}
+	return bands
+}
+
+func (r *DynamicPricingRule) SetBands(bands []OccupancyBand) error {
+	data, err := json.Marshal(bands)
+	if err != nil {
+		return err
+	}
+	r.Bands = data
+	return nil
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// FolioCharge is an incidental charged to a hotel booking during the stay, such as room service
+// or laundry. Charges can only be posted while the guest is checked in and the folio is open.
+type FolioCharge struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	BookingID   uint      `json:"booking_id" gorm:"not null;index"`
+	Category    string    `json:"category" gorm:"size:50"`
+	Description string    `json:"description" gorm:"not null"`
+	Quantity    int       `json:"quantity" gorm:"default:1"`
+	UnitPrice   float64   `json:"unit_price"`
+	Amount      float64   `json:"amount"`
+	PostedBy    string    `json:"posted_by" gorm:"size:100"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (FolioCharge) TableName() string {
+	return "folio_charges"
+}
@@ -28,6 +28,9 @@ type HolidayPackage struct {
 	TermsConditions string                `json:"terms_conditions"`
 	CreatedAt      time.Time              `json:"created_at"`
 	UpdatedAt      time.Time              `json:"updated_at"`
+
+	// Seats on each departure, shared by pending and confirmed bookings and active holds; 0 for no limit
+	DepartureCapacity int `json:"departure_capacity" gorm:"default:0"`
 	
 	// Associations
 	PackageSchedules []PackageSchedule `json:"package_schedules,omitempty" gorm:"foreignKey:PackageID"`
@@ -62,13 +65,17 @@ type PackageBooking struct {
 	NumPassengers    int       `json:"num_passengers" gorm:"not null"`
 	TravelDate       time.Time `json:"travel_date" gorm:"type:date;not null;comment:Start date of the package"`
 	TotalAmount      float64   `json:"total_amount" gorm:"type:decimal(10,2);not null"`
-	BookingStatus    string    `json:"booking_status" gorm:"type:enum('pending','confirmed','cancelled','completed');default:'pending'"`
+	BookingStatus    string    `json:"booking_status" gorm:"type:enum('pending','confirmed','cancelled','completed','expired');default:'pending'"`
+	StatusReason     string    `json:"status_reason" gorm:"type:text"`
 	PaymentStatus    string    `json:"payment_status" gorm:"type:enum('pending','paid','failed','refunded');default:'pending'"`
 	PaymentID        string    `json:"payment_id"`
 	PaymentMethod    string    `json:"payment_method"`
 	SpecialRequests  string    `json:"special_requests"`
+	CancellationRules datatypes.JSON `json:"cancellation_rules" gorm:"comment:Cancellation rules of the package at the time of booking"`
+	RefundAmount     float64   `json:"refund_amount" gorm:"type:decimal(10,2);default:0"`
 	CreatedAt        time.Time `json:"created_at"`
 	UpdatedAt        time.Time `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
 	
 	// Associations
 	Package                HolidayPackage            `json:"package,omitempty" gorm:"foreignKey:PackageID"`
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// ICalFeed is an external iCalendar feed, such as an OTA's export of a room's bookings. The nights
+// it lists are marked unavailable on the room with the feed's block source.
+type ICalFeed struct {
+	ID            uint           `json:"id" gorm:"primaryKey"`
+	RoomID        uint           `json:"roomId" gorm:"column:room_id;not null;index"`
+	Name          string         `json:"name" gorm:"type:varchar(100);not null"` // e.g. Booking.com
+	URL           string         `json:"url" gorm:"column:url;type:varchar(1000);not null"`
+	Status        int            `json:"status" gorm:"default:0"` // 0: Active, 1: Inactive
+	LastSyncedAt  *time.Time     `json:"lastSyncedAt" gorm:"column:last_synced_at"`
+	LastError     string         `json:"lastError" gorm:"column:last_error;type:text"`
+	LastConflicts datatypes.JSON `json:"lastConflicts" gorm:"column:last_conflicts;type:json"` // Nights the feed blocks that we have sold
+	CreatedAt     time.Time      `json:"createdAt" gorm:"column:created_at"`
+	UpdatedAt     time.Time      `json:"updatedAt" gorm:"column:updated_at"`
+}
+
+func (ICalFeed) TableName() string {
+	return "ical_feeds"
+}
+
+// BlockSource tags the RoomAvailability nights blocked by the feed
+func (f *ICalFeed) BlockSource() string {
+	return fmt.Sprintf("ical:%d", f.ID)
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// IdempotencyKey remembers a request made with an Idempotency-Key header and the response it got,
+// so a client retrying the same request gets the same response instead of a second booking or order
+type IdempotencyKey struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Key         string    `json:"key" gorm:"size:255;not null;uniqueIndex:idx_idempotency_key_scope"`
+	Scope       string    `json:"scope" gorm:"size:255;not null;uniqueIndex:idx_idempotency_key_scope"`
+	Fingerprint string    `json:"fingerprint" gorm:"size:64;not null"`
+	Status      string    `json:"status" gorm:"size:20;default:processing"`
+	StatusCode  int       `json:"status_code" gorm:"default:0"`
+	ContentType string    `json:"content_type" gorm:"size:100"`
+	Response    []byte    `json:"-" gorm:"type:mediumblob"`
+	CreatedAt   time.Time `json:"created_at" gorm:"index"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Idempotency key statuses. A key is processing while its first request is being handled.
+const (
+	IdempotencyStatusProcessing = "processing"
+	IdempotencyStatusCompleted  = "completed"
+)
+
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
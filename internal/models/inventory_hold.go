@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// InventoryHold keeps inventory aside for a guest during checkout: the nights of a room, a room of a
+// category's allotment, or seats on a holiday package departure. The booking made with its token
+// takes the inventory over; otherwise it is released once the hold expires.
+type InventoryHold struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	Token          string     `json:"token" gorm:"size:64;not null;uniqueIndex"`
+	InventoryType  string     `json:"inventory_type" gorm:"size:20;not null"`
+	HotelID        *uint      `json:"hotel_id"`
+	RoomID         *uint      `json:"room_id"`
+	RoomCategoryID *uint      `json:"room_category_id"`
+	CheckInDate    *time.Time `json:"check_in_date" gorm:"type:date"`
+	CheckOutDate   *time.Time `json:"check_out_date" gorm:"type:date"`
+	PackageID      *uint      `json:"package_id" gorm:"index:idx_inventory_holds_package_date"`
+	TravelDate     *time.Time `json:"travel_date" gorm:"type:date;index:idx_inventory_holds_package_date"`
+	Seats          int        `json:"seats" gorm:"default:0"`
+	Status         string     `json:"status" gorm:"size:20;default:active;index:idx_inventory_holds_status_expiry"`
+	ExpiresAt      time.Time  `json:"expires_at" gorm:"index:idx_inventory_holds_status_expiry"`
+	// Hotel or package booking that took the hold over
+	BookingID *uint     `json:"booking_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// InventoryTypePackage is the inventory type of holds on holiday package seats
+const InventoryTypePackage = "package"
+
+// Inventory hold statuses. Only active holds keep their inventory.
+const (
+	HoldStatusActive   = "active"
+	HoldStatusConsumed = "consumed"
+	HoldStatusReleased = "released"
+	HoldStatusExpired  = "expired"
+)
+
+func (InventoryHold) TableName() string {
+	return "inventory_holds"
+}
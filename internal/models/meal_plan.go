@@ -15,3 +15,18 @@ type MealPlan struct {
 	CreatedAt         time.Time `json:"createdAt" gorm:"column:created_at"`
 	UpdatedAt         time.Time `json:"updatedAt" gorm:"column:updated_at"`
 }
+
+// MealPlanRate is the per person per night price of a meal plan for a room category. A rate with
+// a date range applies only to nights inside it (both ends inclusive) and takes precedence over
+// an open-ended rate for the same plan.
+type MealPlanRate struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	RoomCategoryID uint       `json:"roomCategoryId" gorm:"column:room_category_id;not null;index:idx_category_plan"`
+	MealPlanID     uint       `json:"mealPlanId" gorm:"column:meal_plan_id;not null;index:idx_category_plan"`
+	PricePerPerson float64    `json:"pricePerPerson" gorm:"column:price_per_person;type:decimal(10,2);not null"`
+	ValidFrom      *time.Time `json:"validFrom" gorm:"column:valid_from;type:date"`
+	ValidTo        *time.Time `json:"validTo" gorm:"column:valid_to;type:date"`
+	Status         int        `json:"status" gorm:"default:0"` // 0: Active, 1: Inactive
+	CreatedAt      time.Time  `json:"createdAt" gorm:"column:created_at"`
+	UpdatedAt      time.Time  `json:"updatedAt" gorm:"column:updated_at"`
+}
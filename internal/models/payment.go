@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // HotelPayment represents payment information
 type HotelPayment struct {
@@ -18,4 +22,7 @@ type HotelPayment struct {
 	GatewayResponse string       `json:"gateway_response"`
 	CreatedAt       time.Time    `json:"created_at"`
 	UpdatedAt       time.Time    `json:"updated_at"`
+
+	// Deleted payments are kept for financial history
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
 }
\ No newline at end of file
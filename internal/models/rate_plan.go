@@ -0,0 +1,71 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// RatePlan groups the pricing rules of a hotel, or of one of its room categories when
+// RoomCategoryID is set
+type RatePlan struct {
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	HotelID        uint           `json:"hotelId" gorm:"column:hotel_id;not null;index"`
+	RoomCategoryID *uint          `json:"roomCategoryId" gorm:"column:room_category_id"` // Optional: null for every category
+	Name           string         `json:"name" gorm:"type:varchar(255);not null"`
+	Description    string         `json:"description" gorm:"type:text"`
+	Status         int            `json:"status" gorm:"default:0"` // 0: Active, 1: Inactive
+	Rules          []RatePlanRule `json:"rules" gorm:"foreignKey:RatePlanID"`
+	CreatedAt      time.Time      `json:"createdAt" gorm:"column:created_at"`
+	UpdatedAt      time.Time      `json:"updatedAt" gorm:"column:updated_at"`
+}
+
+// Adjustments a RatePlanRule makes to a room's nightly rate
+const (
+	RateAdjustmentPercent = "percent" // Value is a percentage added to the rate, e.g. 20 or -10
+	RateAdjustmentAmount  = "amount"  // Value is an amount added to the rate
+	RateAdjustmentFixed   = "fixed"   // Value replaces the rate
+)
+
+// RatePlanRule adjusts the nightly rate on the nights it covers: those between ValidFrom and
+// ValidTo (both inclusive, either may be open) that fall on one of Weekdays (0 = Sunday, empty for
+// every day). When several rules cover a night only the one with the highest Priority applies.
+type RatePlanRule struct {
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	RatePlanID uint           `json:"ratePlanId" gorm:"column:rate_plan_id;not null;index"`
+	Name       string         `json:"name" gorm:"type:varchar(255)"`
+	Priority   int            `json:"priority" gorm:"default:0"`
+	ValidFrom  *time.Time     `json:"validFrom" gorm:"column:valid_from;type:date"`
+	ValidTo    *time.Time     `json:"validTo" gorm:"column:valid_to;type:date"`
+	Weekdays   datatypes.JSON `json:"weekdays" gorm:"type:json"`
+	Adjustment string         `json:"adjustment" gorm:"type:varchar(20);not null"` // percent, amount or fixed
+	Value      float64        `json:"value" gorm:"type:decimal(10,2);not null"`
+	CreatedAt  time.Time      `json:"createdAt" gorm:"column:created_at"`
+	UpdatedAt  time.Time      `json:"updatedAt" gorm:"column:updated_at"`
+}
+
+func (RatePlan) TableName() string {
+	return "rate_plans"
+}
+
+func (RatePlanRule) TableName() string {
+	return "rate_plan_rules"
+}
+
+func (r *RatePlanRule) GetWeekdays() []int {
+	var weekdays []int
+	if r.Weekdays != nil {
+		json.Unmarshal(r.Weekdays, &weekdays)
+	}
+	return weekdays
+}
+
+func (r *RatePlanRule) SetWeekdays(weekdays []int) error {
+	data, err := json.Marshal(weekdays)
+	if err != nil {
+		return err
+	}
+	r.Weekdays = data
+	return nil
+}
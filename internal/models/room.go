@@ -50,11 +50,72 @@ type RoomAvailability struct {
 	Date        time.Time `json:"date"`
 	IsAvailable bool      `json:"isAvailable" gorm:"column:is_available"`
 	Price       float64   `json:"price"` // Dynamic pricing for the date
+	PriceSource string    `json:"priceSource" gorm:"column:price_source;type:varchar(20);default:manual"`
+	BlockSource string    `json:"blockSource" gorm:"column:block_source;type:varchar(50)"` // Set when an external calendar blocked the night
 	CreatedAt   time.Time `json:"createdAt" gorm:"column:created_at"`
 	UpdatedAt   time.Time `json:"updatedAt" gorm:"column:updated_at"`
 }
 
+// Sources of a RoomAvailability price. Manual and dynamic prices override the room's own rates
+// when pricing a booking; base prices are generated from Room.BasePrice by the calendar generator
+// and dynamic prices are set by occupancy-based dynamic pricing.
+const (
+	PriceSourceBase    = "base"
+	PriceSourceManual  = "manual"
+	PriceSourceDynamic = "dynamic"
+)
+
+// Block sources of RoomAvailability nights closed from inside the system. Nights reserved by a
+// booking or a checkout hold are tagged booking, nights closed by hand manual; external calendars
+// use ICalFeed.BlockSource. Only booking nights are reopened when a booking or hold lets go.
+const (
+	BlockSourceBooking = "booking"
+	BlockSourceManual  = "manual"
+)
+
 // TableName overrides the table name used by RoomAvailability to `room_availability`
 func (RoomAvailability) TableName() string {
 	return "room_availability"
 }
+
+// StayRestriction limits the stays that may be sold in a hotel's room category around a date.
+// MinStay and MaxStay (0 for no limit) apply to every stay that includes the night; arrivals are
+// refused on a ClosedToArrival date and departures on a ClosedToDeparture date; StopSell closes
+// the night to sale altogether.
+type StayRestriction struct {
+	ID                uint      `json:"id" gorm:"primaryKey"`
+	HotelID           uint      `json:"hotelId" gorm:"column:hotel_id;not null;uniqueIndex:idx_hotel_category_date"`
+	RoomCategoryID    uint      `json:"roomCategoryId" gorm:"column:room_category_id;not null;uniqueIndex:idx_hotel_category_date"`
+	Date              time.Time `json:"date" gorm:"type:date;not null;uniqueIndex:idx_hotel_category_date"`
+	MinStay           int       `json:"minStay" gorm:"column:min_stay;default:0"`
+	MaxStay           int       `json:"maxStay" gorm:"column:max_stay;default:0"`
+	ClosedToArrival   bool      `json:"closedToArrival" gorm:"column:closed_to_arrival;default:false"`
+	ClosedToDeparture bool      `json:"closedToDeparture" gorm:"column:closed_to_departure;default:false"`
+	StopSell          bool      `json:"stopSell" gorm:"column:stop_sell;default:false"`
+	CreatedAt         time.Time `json:"createdAt" gorm:"column:created_at"`
+	UpdatedAt         time.Time `json:"updatedAt" gorm:"column:updated_at"`
+}
+
+func (StayRestriction) TableName() string {
+	return "stay_restrictions"
+}
+
+// RoomAllotment is the number of rooms of a category a hotel lets us sell on a date without
+// naming the rooms. Unsold rooms go back to the hotel once fewer than ReleaseDays days are left
+// before the night. A Price above zero replaces the category's room rates for the night.
+type RoomAllotment struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	HotelID        uint      `json:"hotelId" gorm:"column:hotel_id;not null;uniqueIndex:idx_allotment_hotel_category_date"`
+	RoomCategoryID uint      `json:"roomCategoryId" gorm:"column:room_category_id;not null;uniqueIndex:idx_allotment_hotel_category_date"`
+	Date           time.Time `json:"date" gorm:"type:date;not null;uniqueIndex:idx_allotment_hotel_category_date"`
+	Allotment      int       `json:"allotment" gorm:"not null;default:0"`
+	Sold           int       `json:"sold" gorm:"not null;default:0"`
+	ReleaseDays    int       `json:"releaseDays" gorm:"column:release_days;default:0"`
+	Price          float64   `json:"price" gorm:"default:0"`
+	CreatedAt      time.Time `json:"createdAt" gorm:"column:created_at"`
+	UpdatedAt      time.Time `json:"updatedAt" gorm:"column:updated_at"`
+}
+
+func (RoomAllotment) TableName() string {
+	return "room_allotments"
+}
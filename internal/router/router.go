@@ -26,8 +26,20 @@ func Initialize(db *gorm.DB, cfg *config.Config) *gin.Engine {
 	roomCategoryService := services.NewRoomCategoryService(db)
 	roomAvailabilityService := services.NewRoomAvailabilityService(db)
 	mealPlanService := services.NewMealPlanService(db)
-	bookingService := services.NewBookingService(db)
+	ratePlanService := services.NewRatePlanService(db)
+	stayRestrictionService := services.NewStayRestrictionService(db)
+	allotmentService := services.NewAllotmentService(db)
+	dynamicPricingService := services.NewDynamicPricingService(db)
+	icalService := services.NewICalService(db, cfg.ICalFeedSecret, cfg.AvailabilityHorizonDays)
+	bookingService := services.NewBookingService(db, cfg.HotelTaxPercent)
+	guestService := services.NewGuestService(db)
+	cancellationPolicyService := services.NewCancellationPolicyService(db)
+	childPolicyService := services.NewChildPolicyService(db)
+	holdService := services.NewHoldService(db, cfg.InventoryHoldTTL)
+	idempotencyService := services.NewIdempotencyService(db, cfg.IdempotencyKeyTTL)
 	paymentService := services.NewPaymentService(db)
+	orderService := services.NewOrderService(db, bookingService)
+	searchService := services.NewSearchService(db, bookingService)
 	reviewService := services.NewReviewService(db)
 	holidayPackageService := services.NewHolidayPackageService(db, cfg.NodeBackendURL)
 
@@ -36,13 +48,28 @@ func Initialize(db *gorm.DB, cfg *config.Config) *gin.Engine {
 	hotelHandler := handlers.NewHotelHandler(hotelService)
 	roomHandler := handlers.NewRoomHandler(roomService)
 	roomCategoryHandler := handlers.NewRoomCategoryHandler(roomCategoryService)
-	roomAvailabilityHandler := handlers.NewRoomAvailabilityHandler(roomAvailabilityService)
+	roomAvailabilityHandler := handlers.NewRoomAvailabilityHandler(roomAvailabilityService, cfg.AvailabilityHorizonDays)
 	mealPlanHandler := handlers.NewMealPlanHandler(mealPlanService)
+	ratePlanHandler := handlers.NewRatePlanHandler(ratePlanService)
+	stayRestrictionHandler := handlers.NewStayRestrictionHandler(stayRestrictionService)
+	allotmentHandler := handlers.NewAllotmentHandler(allotmentService)
+	dynamicPricingHandler := handlers.NewDynamicPricingHandler(dynamicPricingService)
+	icalHandler := handlers.NewICalHandler(icalService)
 	bookingHandler := handlers.NewBookingHandler(bookingService)
-	paymentHandler := handlers.NewPaymentHandler(paymentService, bookingService, cfg.RazorpayID, cfg.RazorpaySecret)
+	guestHandler := handlers.NewGuestHandler(guestService)
+	cancellationPolicyHandler := handlers.NewCancellationPolicyHandler(cancellationPolicyService)
+	childPolicyHandler := handlers.NewChildPolicyHandler(childPolicyService)
+	holdHandler := handlers.NewHoldHandler(holdService)
+	searchHandler := handlers.NewSearchHandler(searchService)
+	orderHandler := handlers.NewOrderHandler(orderService, paymentService, cfg.RazorpayID, cfg.RazorpaySecret)
+	paymentHandler := handlers.NewPaymentHandler(paymentService, bookingService, orderService, cfg.RazorpayID, cfg.RazorpaySecret)
 	reviewHandler := handlers.NewReviewHandler(reviewService)
 	holidayPackageHandler := handlers.NewHolidayPackageHandler(holidayPackageService)
 
+	// Retried bookings and payment orders replay their first response
+	idempotency := middleware.Idempotency(idempotencyService)
+	adminOnly := middleware.AdminOnly(cfg.AdminAPIKey)
+
 	// Health check endpoint
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -61,10 +88,21 @@ func Initialize(db *gorm.DB, cfg *config.Config) *gin.Engine {
 		routes.SetupRoomCategoryRoutes(v1, roomCategoryHandler)
 		routes.SetupRoomAvailabilityRoutes(v1, roomAvailabilityHandler)
 		routes.SetupMealPlanRoutes(v1, mealPlanHandler)
-		routes.SetupBookingRoutes(v1, bookingHandler)
-		routes.SetupPaymentRoutes(v1, paymentHandler)
+		routes.SetupRatePlanRoutes(v1, ratePlanHandler)
+		routes.SetupStayRestrictionRoutes(v1, stayRestrictionHandler)
+		routes.SetupAllotmentRoutes(v1, allotmentHandler)
+		routes.SetupDynamicPricingRoutes(v1, dynamicPricingHandler)
+		routes.SetupICalRoutes(v1, icalHandler)
+		routes.SetupBookingRoutes(v1, bookingHandler, idempotency, adminOnly)
+		routes.SetupGuestRoutes(v1, guestHandler)
+		routes.SetupOrderRoutes(v1, orderHandler)
+		routes.SetupSearchRoutes(v1, searchHandler)
+		routes.SetupPaymentRoutes(v1, paymentHandler, idempotency, adminOnly)
 		routes.SetupReviewRoutes(v1, reviewHandler)
-		routes.SetupHolidayPackageRoutes(v1, holidayPackageHandler)
+		routes.SetupHolidayPackageRoutes(v1, holidayPackageHandler, idempotency, adminOnly)
+		routes.SetupCancellationPolicyRoutes(v1, cancellationPolicyHandler)
+		routes.SetupChildPolicyRoutes(v1, childPolicyHandler)
+		routes.SetupHoldRoutes(v1, holdHandler)
 	}
 
 	return r
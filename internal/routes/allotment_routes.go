@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"flyola-services/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupAllotmentRoutes(router *gin.RouterGroup, allotmentHandler *handlers.AllotmentHandler) {
+	router.GET("/hotels/:id/allotments", allotmentHandler.GetHotelAllotments)
+	router.PUT("/hotels/:id/allotments", allotmentHandler.SetHotelAllotments) // Admin only
+	router.DELETE("/allotments/:id", allotmentHandler.DeleteAllotment)        // Admin only
+}
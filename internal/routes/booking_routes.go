@@ -6,14 +6,29 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func SetupBookingRoutes(router *gin.RouterGroup, bookingHandler *handlers.BookingHandler) {
+func SetupBookingRoutes(router *gin.RouterGroup, bookingHandler *handlers.BookingHandler, idempotency, adminOnly gin.HandlerFunc) {
 	bookings := router.Group("/bookings")
 	{
 		bookings.GET("", bookingHandler.GetBookings)
-		bookings.POST("", bookingHandler.CreateBooking)
+		bookings.POST("", idempotency, bookingHandler.CreateBooking)
+		bookings.POST("/quote", bookingHandler.QuoteBooking)
+		bookings.GET("/lookup", bookingHandler.LookupBooking)
+		bookings.GET("/archive", adminOnly, bookingHandler.GetArchivedBookings)
 		bookings.GET("/:id", bookingHandler.GetBookingByID)
 		bookings.PUT("/:id", bookingHandler.UpdateBooking)
-		bookings.DELETE("/:id", bookingHandler.DeleteBooking)
+		bookings.DELETE("/:id", adminOnly, bookingHandler.DeleteBooking)
+		bookings.POST("/:id/restore", adminOnly, bookingHandler.RestoreBooking)
+		bookings.POST("/:id/modify", bookingHandler.ModifyBooking)
+		bookings.POST("/:id/assign-room", bookingHandler.AssignRoom)
+		bookings.POST("/:id/check-in", bookingHandler.CheckIn)
+		bookings.POST("/:id/check-out", bookingHandler.CheckOut)
+		bookings.GET("/:id/folio", bookingHandler.GetFolio)
+		bookings.POST("/:id/folio/charges", bookingHandler.PostFolioCharge)
+		bookings.GET("/:id/cancellation-preview", bookingHandler.PreviewCancellation)
 		bookings.PUT("/:id/cancel", bookingHandler.CancelBooking)
+		bookings.PUT("/:id/status", bookingHandler.UpdateBookingStatus)
+		bookings.GET("/:id/history", bookingHandler.GetBookingHistory)
 	}
+
+	router.GET("/hotels/:id/front-desk", bookingHandler.GetFrontDeskList)
 }
\ No newline at end of file
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"flyola-services/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupCancellationPolicyRoutes(router *gin.RouterGroup, policyHandler *handlers.CancellationPolicyHandler) {
+	router.GET("/hotels/:id/cancellation-policy", policyHandler.GetHotelPolicy)
+	router.PUT("/hotels/:id/cancellation-policy", policyHandler.SetHotelPolicy) // Admin only
+	router.GET("/holiday-packages/:id/cancellation-policy", policyHandler.GetPackagePolicy)
+	router.PUT("/holiday-packages/:id/cancellation-policy", policyHandler.SetPackagePolicy) // Admin only
+}
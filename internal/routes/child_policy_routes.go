@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"flyola-services/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupChildPolicyRoutes(router *gin.RouterGroup, childPolicyHandler *handlers.ChildPolicyHandler) {
+	router.GET("/hotels/:id/child-policy", childPolicyHandler.GetHotelPolicy)
+	router.PUT("/hotels/:id/child-policy", childPolicyHandler.SetHotelPolicy)       // Admin only
+	router.DELETE("/hotels/:id/child-policy", childPolicyHandler.DeleteHotelPolicy) // Admin only
+}
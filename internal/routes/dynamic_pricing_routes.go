@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"flyola-services/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupDynamicPricingRoutes(router *gin.RouterGroup, dynamicPricingHandler *handlers.DynamicPricingHandler) {
+	// Admin only
+	router.GET("/hotels/:id/dynamic-pricing", dynamicPricingHandler.GetHotelRules)
+	router.PUT("/hotels/:id/dynamic-pricing", dynamicPricingHandler.SetRule)
+	router.GET("/hotels/:id/dynamic-pricing/preview", dynamicPricingHandler.PreviewPrices)
+	router.POST("/hotels/:id/dynamic-pricing/apply", dynamicPricingHandler.ApplyPrices)
+	router.GET("/hotels/:id/dynamic-pricing/changes", dynamicPricingHandler.GetPriceChanges)
+	router.DELETE("/dynamic-pricing/:id", dynamicPricingHandler.DeleteRule)
+}
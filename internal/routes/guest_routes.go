@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"flyola-services/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupGuestRoutes(router *gin.RouterGroup, guestHandler *handlers.GuestHandler) {
+	guests := router.Group("/bookings/:id/guests")
+	{
+		guests.GET("", guestHandler.GetGuests)
+		guests.POST("", guestHandler.AddGuest)
+		guests.PUT("/:guestId", guestHandler.UpdateGuest)
+		guests.DELETE("/:guestId", guestHandler.DeleteGuest)
+	}
+}
@@ -6,7 +6,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func SetupHolidayPackageRoutes(router *gin.RouterGroup, holidayPackageHandler *handlers.HolidayPackageHandler) {
+func SetupHolidayPackageRoutes(router *gin.RouterGroup, holidayPackageHandler *handlers.HolidayPackageHandler, idempotency, adminOnly gin.HandlerFunc) {
 	packages := router.Group("/holiday-packages")
 	{
 		// Package management routes
@@ -19,15 +19,18 @@ func SetupHolidayPackageRoutes(router *gin.RouterGroup, holidayPackageHandler *h
 		packages.DELETE("/:id", holidayPackageHandler.DeletePackage) // Admin only
 
 		// Booking routes
-		packages.POST("/book", holidayPackageHandler.CreatePackageBooking)
+		packages.POST("/book", idempotency, holidayPackageHandler.CreatePackageBooking)
 		packages.POST("/book/:id/confirm", holidayPackageHandler.ConfirmPackageBooking)
 		
 		// Booking management routes
 		packages.GET("/bookings/:id", holidayPackageHandler.GetBookingByID)
 		packages.GET("/bookings/reference/:reference", holidayPackageHandler.GetBookingByReference)
+		packages.GET("/bookings/:id/cancellation-preview", holidayPackageHandler.PreviewPackageCancellation)
 		packages.DELETE("/bookings/:id", holidayPackageHandler.CancelPackageBooking)
 		
 		// Admin routes
 		packages.GET("/admin/bookings", holidayPackageHandler.GetAllPackageBookings) // Admin only
+		packages.DELETE("/admin/bookings/:id", adminOnly, holidayPackageHandler.DeletePackageBooking)
+		packages.POST("/bookings/:id/restore", adminOnly, holidayPackageHandler.RestorePackageBooking)
 	}
 }
\ No newline at end of file
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"flyola-services/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupICalRoutes(router *gin.RouterGroup, icalHandler *handlers.ICalHandler) {
+	router.GET("/rooms/:id/calendar.ics", icalHandler.ExportRoomCalendar)
+	router.GET("/rooms/:id/ical-feeds", icalHandler.GetRoomFeeds) // Admin only
+	router.POST("/rooms/:id/ical-feeds", icalHandler.CreateFeed)  // Admin only
+	router.DELETE("/ical-feeds/:id", icalHandler.DeleteFeed)      // Admin only
+	router.POST("/ical-feeds/:id/sync", icalHandler.SyncFeed)     // Admin only
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"flyola-services/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupHoldRoutes(router *gin.RouterGroup, holdHandler *handlers.HoldHandler) {
+	router.POST("/holds", holdHandler.CreateHold)
+	router.GET("/holds/:token", holdHandler.GetHold)
+	router.DELETE("/holds/:token", holdHandler.ReleaseHold)
+}
@@ -15,4 +15,15 @@ func SetupMealPlanRoutes(router *gin.RouterGroup, mealPlanHandler *handlers.Meal
 		mealPlans.PUT("/:id", mealPlanHandler.UpdateMealPlan)
 		mealPlans.DELETE("/:id", mealPlanHandler.DeleteMealPlan)
 	}
+
+	mealPlanRates := router.Group("/meal-plan-rates")
+	{
+		mealPlanRates.PUT("/:id", mealPlanHandler.UpdateMealPlanRate)
+		mealPlanRates.DELETE("/:id", mealPlanHandler.DeleteMealPlanRate)
+	}
+
+	// Meal plan pricing per room category
+	router.GET("/room-categories/:id/meal-plan-rates", mealPlanHandler.GetMealPlanRates)
+	router.POST("/room-categories/:id/meal-plan-rates", mealPlanHandler.CreateMealPlanRate)
+	router.GET("/room-categories/:id/meal-plans", mealPlanHandler.GetMealPlanOffers)
 }
\ No newline at end of file
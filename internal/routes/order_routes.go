@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"flyola-services/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupOrderRoutes(router *gin.RouterGroup, orderHandler *handlers.OrderHandler) {
+	orders := router.Group("/orders")
+	{
+		orders.POST("", orderHandler.CreateOrder)
+		orders.GET("/:id", orderHandler.GetOrderByID)
+		orders.POST("/:id/payment-order", orderHandler.CreatePaymentOrder)
+		orders.GET("/:id/cancellation-preview", orderHandler.PreviewCancellation)
+		orders.PUT("/:id/cancel", orderHandler.CancelOrder)
+	}
+}
@@ -6,11 +6,15 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func SetupPaymentRoutes(router *gin.RouterGroup, paymentHandler *handlers.PaymentHandler) {
+func SetupPaymentRoutes(router *gin.RouterGroup, paymentHandler *handlers.PaymentHandler, idempotency, adminOnly gin.HandlerFunc) {
 	payments := router.Group("/payments")
 	{
 		// Payment processing routes
-		payments.POST("/create-order", paymentHandler.CreateOrder)
+		payments.POST("/create-order", idempotency, paymentHandler.CreateOrder)
 		payments.POST("/verify", paymentHandler.VerifyPayment)
+
+		// Admin routes
+		payments.DELETE("/:id", adminOnly, paymentHandler.DeletePayment)
+		payments.POST("/:id/restore", adminOnly, paymentHandler.RestorePayment)
 	}
 }
\ No newline at end of file
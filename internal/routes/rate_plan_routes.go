@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"flyola-services/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupRatePlanRoutes(router *gin.RouterGroup, ratePlanHandler *handlers.RatePlanHandler) {
+	ratePlans := router.Group("/rate-plans")
+	{
+		ratePlans.GET("/:id", ratePlanHandler.GetRatePlan)
+		ratePlans.PUT("/:id", ratePlanHandler.UpdateRatePlan)    // Admin only
+		ratePlans.DELETE("/:id", ratePlanHandler.DeleteRatePlan) // Admin only
+	}
+
+	router.GET("/hotels/:id/rate-plans", ratePlanHandler.GetHotelRatePlans)
+	router.POST("/hotels/:id/rate-plans", ratePlanHandler.CreateRatePlan) // Admin only
+	router.GET("/rooms/:id/rates", ratePlanHandler.GetRoomRates)
+}
@@ -12,7 +12,11 @@ func SetupRoomAvailabilityRoutes(rg *gin.RouterGroup, handler *handlers.RoomAvai
 		roomAvailability.GET("", handler.GetRoomAvailability)
 		roomAvailability.GET("/:id", handler.GetRoomAvailabilityByID)
 		roomAvailability.POST("", handler.CreateRoomAvailability)
+		roomAvailability.POST("/bulk", handler.BulkUpdateAvailability) // Admin only
 		roomAvailability.PUT("/:id", handler.UpdateRoomAvailability)
 		roomAvailability.DELETE("/:id", handler.DeleteRoomAvailability)
 	}
+
+	rg.POST("/hotels/:id/availability-calendar", handler.GenerateCalendar) // Admin only
+	rg.GET("/hotels/:id/availability-matrix", handler.GetAvailabilityMatrix)
 }
\ No newline at end of file
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"flyola-services/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupSearchRoutes(router *gin.RouterGroup, searchHandler *handlers.SearchHandler) {
+	search := router.Group("/search")
+	{
+		search.GET("/hotels", searchHandler.SearchHotels)
+	}
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"flyola-services/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupStayRestrictionRoutes(router *gin.RouterGroup, restrictionHandler *handlers.StayRestrictionHandler) {
+	router.GET("/hotels/:id/stay-restrictions", restrictionHandler.GetHotelRestrictions)
+	router.PUT("/hotels/:id/stay-restrictions", restrictionHandler.SetHotelRestrictions) // Admin only
+	router.DELETE("/stay-restrictions/:id", restrictionHandler.DeleteRestriction)        // Admin only
+}
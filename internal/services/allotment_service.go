@@ -0,0 +1,259 @@
+package services
+
+import (
+	"errors"
+	"flyola-services/internal/models"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxAllotmentDays caps the date range of a single allotment update or listing
+const maxAllotmentDays = 366
+
+// AllotmentUnavailableError is returned when a category booking needs a night whose allotment is
+// missing, released or sold out
+type AllotmentUnavailableError struct {
+	RoomCategoryID uint
+	Dates          []string
+}
+
+func (e *AllotmentUnavailableError) Error() string {
+	return fmt.Sprintf("no allotment left for room category %d on %s", e.RoomCategoryID, strings.Join(e.Dates, ", "))
+}
+
+// AllotmentUpdate sets the same allotment on every date of a range (both ends inclusive),
+// optionally only on some weekdays (0 = Sunday). Rooms already sold are kept.
+type AllotmentUpdate struct {
+	RoomCategoryID uint      `json:"roomCategoryId" binding:"required"`
+	StartDate      time.Time `json:"startDate" binding:"required"`
+	EndDate        time.Time `json:"endDate" binding:"required"`
+	Weekdays       []int     `json:"weekdays"`
+	Allotment      int       `json:"allotment"`
+	ReleaseDays    int       `json:"releaseDays"`
+	Price          float64   `json:"price"`
+}
+
+type AllotmentService struct {
+	db *gorm.DB
+}
+
+func NewAllotmentService(db *gorm.DB) *AllotmentService {
+	return &AllotmentService{db: db}
+}
+
+// GetAllotments lists a hotel's allotments from one date to another (both inclusive), optionally
+// for a single room category
+func (s *AllotmentService) GetAllotments(hotelID uint, roomCategoryID *uint, from, to time.Time) ([]models.RoomAllotment, error) {
+	if _, err := datesInRange(from, to, nil, maxAllotmentDays); err != nil {
+		return nil, err
+	}
+
+	query := s.db.Where("hotel_id = ? AND date >= ? AND date <= ?", hotelID, dateOnly(from), dateOnly(to))
+	if roomCategoryID != nil {
+		query = query.Where("room_category_id = ?", *roomCategoryID)
+	}
+
+	var allotments []models.RoomAllotment
+	err := query.Order("date, room_category_id").Find(&allotments).Error
+	return allotments, err
+}
+
+// SetAllotments writes the allotment of update for a hotel's room category and returns how many
+// dates were set. An allotment cannot go below the rooms already sold on a date.
+func (s *AllotmentService) SetAllotments(hotelID uint, update *AllotmentUpdate) (int, error) {
+	if update.Allotment < 0 || update.ReleaseDays < 0 || update.Price < 0 {
+		return 0, &BookingValidationError{Message: "allotment, releaseDays and price cannot be negative"}
+	}
+	dates, err := datesInRange(update.StartDate, update.EndDate, update.Weekdays, maxAllotmentDays)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.db.Select("id").First(&models.Hotel{}, hotelID).Error; err != nil {
+		return 0, err
+	}
+	var category models.RoomCategory
+	if err := s.db.Select("id", "hotel_id").First(&category, update.RoomCategoryID).Error; err != nil {
+		return 0, err
+	}
+	if category.HotelID != nil && *category.HotelID != hotelID {
+		return 0, &BookingValidationError{Message: "roomCategoryId belongs to another hotel"}
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var oversold []models.RoomAllotment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("hotel_id = ? AND room_category_id = ? AND date IN ?", hotelID, category.ID, dates).
+			Where("sold > ?", update.Allotment).
+			Order("date").Find(&oversold).Error; err != nil {
+			return err
+		}
+		if len(oversold) > 0 {
+			return &BookingValidationError{
+				Message: fmt.Sprintf("%d room(s) are already sold on %s; the allotment cannot be lower",
+					oversold[0].Sold, oversold[0].Date.Format("2006-01-02")),
+			}
+		}
+
+		allotments := make([]models.RoomAllotment, len(dates))
+		for i, date := range dates {
+			allotments[i] = models.RoomAllotment{
+				HotelID:        hotelID,
+				RoomCategoryID: category.ID,
+				Date:           date,
+				Allotment:      update.Allotment,
+				ReleaseDays:    update.ReleaseDays,
+				Price:          update.Price,
+			}
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "hotel_id"}, {Name: "room_category_id"}, {Name: "date"}},
+			DoUpdates: clause.AssignmentColumns([]string{"allotment", "release_days", "price", "updated_at"}),
+		}).CreateInBatches(&allotments, bulkWriteBatch).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(dates), nil
+}
+
+// DeleteAllotment removes an allotment date that has no rooms sold
+func (s *AllotmentService) DeleteAllotment(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var allotment models.RoomAllotment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&allotment, id).Error; err != nil {
+			return err
+		}
+		if allotment.Sold > 0 {
+			return &BookingValidationError{
+				Message: fmt.Sprintf("%d room(s) are already sold from this allotment", allotment.Sold),
+			}
+		}
+		return tx.Delete(&allotment).Error
+	})
+}
+
+// allotmentReleased tells whether the unsold rooms of an allotment have gone back to the hotel
+func allotmentReleased(allotment *models.RoomAllotment, today time.Time) bool {
+	return today.AddDate(0, 0, allotment.ReleaseDays).After(dateOnly(allotment.Date))
+}
+
+// reserveAllotmentNights takes one room of a category's allotment for every night of a stay. The
+// allotment rows are locked, so concurrent bookings cannot sell the same last room twice.
+func reserveAllotmentNights(tx *gorm.DB, hotelID, roomCategoryID uint, checkIn, checkOut time.Time) error {
+	var allotments []models.RoomAllotment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("hotel_id = ? AND room_category_id = ? AND date >= ? AND date < ?", hotelID, roomCategoryID, checkIn, checkOut).
+		Find(&allotments).Error; err != nil {
+		return err
+	}
+
+	today := dateOnly(time.Now())
+	sellable := make(map[string]bool, len(allotments))
+	ids := make([]uint, 0, len(allotments))
+	for i := range allotments {
+		allotment := &allotments[i]
+		if allotment.Sold < allotment.Allotment && !allotmentReleased(allotment, today) {
+			sellable[allotment.Date.Format("2006-01-02")] = true
+			ids = append(ids, allotment.ID)
+		}
+	}
+
+	var unavailable []string
+	for _, night := range stayNights(checkIn, checkOut) {
+		if key := night.Format("2006-01-02"); !sellable[key] {
+			unavailable = append(unavailable, key)
+		}
+	}
+	if len(unavailable) > 0 {
+		sort.Strings(unavailable)
+		return &AllotmentUnavailableError{RoomCategoryID: roomCategoryID, Dates: unavailable}
+	}
+
+	return tx.Model(&models.RoomAllotment{}).Where("id IN ?", ids).
+		Update("sold", gorm.Expr("sold + 1")).Error
+}
+
+// releaseAllotmentNights gives the room a stay took back to its category's allotment
+func releaseAllotmentNights(tx *gorm.DB, hotelID, roomCategoryID uint, checkIn, checkOut time.Time) error {
+	return tx.Model(&models.RoomAllotment{}).
+		Where("hotel_id = ? AND room_category_id = ? AND date >= ? AND date < ?", hotelID, roomCategoryID, dateOnly(checkIn), dateOnly(checkOut)).
+		Where("sold > ?", 0).
+		Update("sold", gorm.Expr("sold - 1")).Error
+}
+
+// allotmentPriceOverrides returns the allotment prices of a category for the nights of a stay,
+// keyed by date. Allotments without a price are left out so the room rates apply.
+func allotmentPriceOverrides(tx *gorm.DB, hotelID, roomCategoryID uint, checkIn, checkOut time.Time) (map[string]float64, error) {
+	var allotments []models.RoomAllotment
+	if err := tx.Where("hotel_id = ? AND room_category_id = ? AND date >= ? AND date < ? AND price > ?", hotelID, roomCategoryID, checkIn, checkOut, 0).
+		Find(&allotments).Error; err != nil {
+		return nil, err
+	}
+	overrides := make(map[string]float64, len(allotments))
+	for _, allotment := range allotments {
+		overrides[allotment.Date.Format("2006-01-02")] = allotment.Price
+	}
+	return overrides, nil
+}
+
+// allotmentRateRoom returns the room whose rates price a category booking: the cheapest active
+// room of the category in the hotel
+func allotmentRateRoom(tx *gorm.DB, hotelID, roomCategoryID uint) (*models.Room, error) {
+	var room models.Room
+	err := tx.Preload("RoomCategory").
+		Where("hotel_id = ? AND room_category_id = ? AND status = ?", hotelID, roomCategoryID, 0).
+		Order("base_price, id").First(&room).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, &BookingValidationError{Message: "The hotel has no active room of this category to price the booking"}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &room, nil
+}
+
+// allotmentRoomsLeft returns, by hotel and then room category, how many rooms of an allotment can
+// still be booked for every night of a stay. Categories missing an allotment on any night are left out.
+func allotmentRoomsLeft(tx *gorm.DB, hotelIDs, roomCategoryIDs []uint, checkIn, checkOut time.Time) (map[uint]map[uint]int, error) {
+	var allotments []models.RoomAllotment
+	if err := tx.Where("hotel_id IN ? AND room_category_id IN ? AND date >= ? AND date < ?", hotelIDs, roomCategoryIDs, checkIn, checkOut).
+		Find(&allotments).Error; err != nil {
+		return nil, err
+	}
+
+	type categoryKey struct{ hotelID, roomCategoryID uint }
+	today := dateOnly(time.Now())
+	nights := make(map[categoryKey]int)
+	left := make(map[categoryKey]int)
+	for i := range allotments {
+		allotment := &allotments[i]
+		key := categoryKey{allotment.HotelID, allotment.RoomCategoryID}
+		remaining := allotment.Allotment - allotment.Sold
+		if allotmentReleased(allotment, today) || remaining < 0 {
+			remaining = 0
+		}
+		if count, ok := left[key]; !ok || remaining < count {
+			left[key] = remaining
+		}
+		nights[key]++
+	}
+
+	stay := len(stayNights(checkIn, checkOut))
+	byHotel := make(map[uint]map[uint]int)
+	for key, count := range left {
+		if nights[key] < stay || count == 0 {
+			continue
+		}
+		if byHotel[key.hotelID] == nil {
+			byHotel[key.hotelID] = make(map[uint]int)
+		}
+		byHotel[key.hotelID][key.roomCategoryID] = count
+	}
+	return byHotel, nil
+}
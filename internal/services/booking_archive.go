@@ -0,0 +1,108 @@
+package services
+
+import (
+	"flyola-services/internal/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// hotelBookingArchiveTable holds hotel bookings moved out of hotel_bookings by ArchiveBookings
+const hotelBookingArchiveTable = "hotel_bookings_archive"
+
+// archivableBookingStatuses are the final statuses after which a hotel booking is archived
+var archivableBookingStatuses = []string{
+	models.BookingStatusCheckedOut,
+	models.BookingStatusCancelled,
+	models.BookingStatusNoShow,
+	models.BookingStatusExpired,
+}
+
+// ArchiveBookings moves up to batchSize hotel bookings, with their guests, payments, folio charges
+// and status history, into the archive tables. Reviews stay with their hotel and lose the link to
+// the archived booking. A booking is archived once it ended with a final status, or was
+// deleted, more than retentionDays ago. Rows are claimed with SKIP LOCKED so several server
+// replicas can run this at the same time.
+func (s *BookingService) ArchiveBookings(retentionDays, batchSize int) (int, error) {
+	cutoff := dateOnly(time.Now()).AddDate(0, 0, -retentionDays)
+
+	archived := 0
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var bookings []models.HotelBooking
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).Select("id").
+			Where("(booking_status IN ? AND check_out_date < ?) OR deleted_at < ?", archivableBookingStatuses, cutoff, cutoff).
+			Order("id").Limit(batchSize).Find(&bookings).Error; err != nil {
+			return err
+		}
+		if len(bookings) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, len(bookings))
+		for i, booking := range bookings {
+			ids[i] = booking.ID
+		}
+
+		archivedAt := time.Now()
+		for _, table := range []struct{ name, column string }{
+			{"hotel_bookings", "id"},
+			{"hotel_guests", "booking_id"},
+			{"hotel_payments", "booking_id"},
+			{"folio_charges", "booking_id"},
+			{"booking_status_history", "booking_id"},
+		} {
+			if err := archiveRows(tx, table.name, table.column, ids, archivedAt); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("booking_id IN ?", ids).Delete(&models.HotelGuest{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("booking_id IN ?", ids).Delete(&models.FolioCharge{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("booking_id IN ?", ids).Delete(&models.BookingStatusHistory{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.HotelReview{}).Where("booking_id IN ?", ids).Update("booking_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("booking_id IN ?", ids).Delete(&models.HotelPayment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("id IN ?", ids).Delete(&models.HotelBooking{}).Error; err != nil {
+			return err
+		}
+
+		archived = len(ids)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return archived, nil
+}
+
+// archiveRows copies the rows of table whose column is one of ids into the table's archive. The
+// columns are read from the live table, so an archive that has fallen behind it fails loudly
+// instead of losing data.
+func archiveRows(tx *gorm.DB, table, column string, ids []uint, archivedAt time.Time) error {
+	columnTypes, err := tx.Migrator().ColumnTypes(table)
+	if err != nil {
+		return err
+	}
+	names := make([]string, len(columnTypes))
+	for i, columnType := range columnTypes {
+		names[i] = "`" + columnType.Name() + "`"
+	}
+	columns := strings.Join(names, ", ")
+
+	return tx.Exec(
+		fmt.Sprintf("INSERT INTO `%s_archive` (%s, `archived_at`) SELECT %s, ? FROM `%s` WHERE `%s` IN ?", table, columns, columns, table, column),
+		archivedAt, ids,
+	).Error
+}
@@ -0,0 +1,288 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"flyola-services/internal/models"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Page sizes of booking lists
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+// listCursorTimeFormat keeps enough precision for datetime(3) columns
+const listCursorTimeFormat = "2006-01-02 15:04:05.000"
+
+// BookingListFilter narrows and orders a list of hotel or package bookings. From and To are
+// inclusive dates: hotel bookings staying any night between them, or package bookings travelling
+// between them. Search matches the guest name, guest phone or booking reference. Sort names a
+// field, descending when prefixed with "-". Cursor is the next_cursor of the previous page.
+// Archived lists archived hotel bookings instead of live ones.
+type BookingListFilter struct {
+	HotelID       *uint
+	PackageID     *uint
+	Status        string
+	PaymentStatus string
+	From          *time.Time
+	To            *time.Time
+	Search        string
+	Sort          string
+	Cursor        string
+	Limit         int
+	Archived      bool
+}
+
+// ListPage describes one page of a list. NextCursor is empty on the last page.
+type ListPage struct {
+	Total      int64  `json:"total"`
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// hotelBookingSortColumns are the fields hotel booking lists can be sorted by
+var hotelBookingSortColumns = map[string]string{
+	"created_at":     "created_at",
+	"check_in_date":  "check_in_date",
+	"check_out_date": "check_out_date",
+	"final_amount":   "final_amount",
+	"guest_name":     "guest_name",
+}
+
+// packageBookingSortColumns are the fields package booking lists can be sorted by
+var packageBookingSortColumns = map[string]string{
+	"created_at":   "created_at",
+	"travel_date":  "travel_date",
+	"total_amount": "total_amount",
+	"guest_name":   "guest_name",
+}
+
+// listSort orders a list by one column, with the id breaking ties so every row has a fixed place
+type listSort struct {
+	column string
+	desc   bool
+}
+
+// listCursor is the position after the last row of a page: its sort value and id
+type listCursor struct {
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+// ListBookings returns a page of hotel bookings matching filter, with the total number of matches
+func (s *BookingService) ListBookings(filter *BookingListFilter) ([]models.HotelBooking, *ListPage, error) {
+	order, err := parseListSort(filter.Sort, hotelBookingSortColumns)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query := s.db.Model(&models.HotelBooking{})
+	if filter.Archived {
+		// Archived bookings that had been deleted are listed too
+		query = s.db.Unscoped().Table(hotelBookingArchiveTable)
+	}
+	if filter.HotelID != nil {
+		query = query.Where("hotel_id = ?", *filter.HotelID)
+	}
+	if filter.Status != "" {
+		query = query.Where("booking_status = ?", filter.Status)
+	}
+	if filter.PaymentStatus != "" {
+		query = query.Where("payment_status = ?", filter.PaymentStatus)
+	}
+	if filter.From != nil {
+		query = query.Where("check_out_date > ?", dateOnly(*filter.From))
+	}
+	if filter.To != nil {
+		query = query.Where("check_in_date < ?", dateOnly(*filter.To).AddDate(0, 0, 1))
+	}
+	if term := strings.TrimSpace(filter.Search); term != "" {
+		pattern := likePattern(term)
+		query = query.Where("guest_name LIKE ? OR guest_phone LIKE ? OR booking_reference LIKE ?", pattern, pattern, pattern)
+	}
+
+	var bookings []models.HotelBooking
+	page, err := listPage(query, order, filter, &bookings, func() (string, uint) {
+		last := bookings[len(bookings)-1]
+		return hotelBookingSortValue(&last, order.column), last.ID
+	}, "Hotel", "Room")
+	if err != nil {
+		return nil, nil, err
+	}
+	return bookings, page, nil
+}
+
+// hotelBookingSortValue returns the value of the sort column of a booking for a cursor
+func hotelBookingSortValue(booking *models.HotelBooking, column string) string {
+	switch column {
+	case "check_in_date":
+		return booking.CheckInDate.Format(listCursorTimeFormat)
+	case "check_out_date":
+		return booking.CheckOutDate.Format(listCursorTimeFormat)
+	case "final_amount":
+		return fmt.Sprint(booking.FinalAmount)
+	case "guest_name":
+		return booking.GuestName
+	}
+	return booking.CreatedAt.Format(listCursorTimeFormat)
+}
+
+// ListPackageBookings returns a page of package bookings matching filter, with the total number of
+// matches
+func (s *HolidayPackageService) ListPackageBookings(filter *BookingListFilter) ([]models.PackageBooking, *ListPage, error) {
+	order, err := parseListSort(filter.Sort, packageBookingSortColumns)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query := s.db.Model(&models.PackageBooking{})
+	if filter.PackageID != nil {
+		query = query.Where("package_id = ?", *filter.PackageID)
+	}
+	if filter.Status != "" {
+		query = query.Where("booking_status = ?", filter.Status)
+	}
+	if filter.PaymentStatus != "" {
+		query = query.Where("payment_status = ?", filter.PaymentStatus)
+	}
+	if filter.From != nil {
+		query = query.Where("travel_date >= ?", filter.From.Format("2006-01-02"))
+	}
+	if filter.To != nil {
+		query = query.Where("travel_date <= ?", filter.To.Format("2006-01-02"))
+	}
+	if term := strings.TrimSpace(filter.Search); term != "" {
+		pattern := likePattern(term)
+		query = query.Where("guest_name LIKE ? OR guest_phone LIKE ? OR booking_reference LIKE ? OR pnr LIKE ?", pattern, pattern, pattern, pattern)
+	}
+
+	var bookings []models.PackageBooking
+	page, err := listPage(query, order, filter, &bookings, func() (string, uint) {
+		last := bookings[len(bookings)-1]
+		return packageBookingSortValue(&last, order.column), last.ID
+	}, "Package", "Passengers")
+	if err != nil {
+		return nil, nil, err
+	}
+	return bookings, page, nil
+}
+
+// packageBookingSortValue returns the value of the sort column of a package booking for a cursor
+func packageBookingSortValue(booking *models.PackageBooking, column string) string {
+	switch column {
+	case "travel_date":
+		return booking.TravelDate.Format("2006-01-02")
+	case "total_amount":
+		return fmt.Sprint(booking.TotalAmount)
+	case "guest_name":
+		return booking.GuestName
+	}
+	return booking.CreatedAt.Format(listCursorTimeFormat)
+}
+
+// listPage counts the rows of query, then loads the page after filter.Cursor into dest with the
+// given associations. lastRow returns the sort value and id of the last loaded row, to build the
+// next cursor.
+func listPage(query *gorm.DB, order listSort, filter *BookingListFilter, dest interface{}, lastRow func() (string, uint), preloads ...string) (*ListPage, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+
+	page := &ListPage{Limit: limit}
+	if err := query.Session(&gorm.Session{}).Count(&page.Total).Error; err != nil {
+		return nil, err
+	}
+
+	direction, comparison := "ASC", ">"
+	if order.desc {
+		direction, comparison = "DESC", "<"
+	}
+	if filter.Cursor != "" {
+		cursor, err := decodeListCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(
+			fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", order.column, comparison),
+			cursor.Value, cursor.Value, cursor.ID,
+		)
+	}
+
+	for _, association := range preloads {
+		query = query.Preload(association)
+	}
+
+	// One row more than the page tells whether there is a next page
+	result := query.Order(fmt.Sprintf("%s %s, id %s", order.column, direction, direction)).Limit(limit + 1).Find(dest)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if int(result.RowsAffected) > limit {
+		trimListPage(dest, limit)
+		value, id := lastRow()
+		page.NextCursor = listCursor{Value: value, ID: id}.encode()
+	}
+	return page, nil
+}
+
+// trimListPage drops the extra row loaded to detect a next page
+func trimListPage(dest interface{}, limit int) {
+	switch rows := dest.(type) {
+	case *[]models.HotelBooking:
+		*rows = (*rows)[:limit]
+	case *[]models.PackageBooking:
+		*rows = (*rows)[:limit]
+	}
+}
+
+// parseListSort resolves a sort such as "-created_at" against the columns a list can be sorted by.
+// Lists default to the newest first.
+func parseListSort(value string, columns map[string]string) (listSort, error) {
+	if value == "" {
+		return listSort{column: "created_at", desc: true}, nil
+	}
+	desc := strings.HasPrefix(value, "-")
+	column, ok := columns[strings.TrimPrefix(value, "-")]
+	if !ok {
+		fields := make([]string, 0, len(columns))
+		for field := range columns {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		return listSort{}, &BookingValidationError{Message: "sort must be one of " + strings.Join(fields, ", ") + ", optionally prefixed with -"}
+	}
+	return listSort{column: column, desc: desc}, nil
+}
+
+func (c listCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeListCursor(value string) (*listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, &BookingValidationError{Message: "Invalid cursor"}
+	}
+	var cursor listCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, &BookingValidationError{Message: "Invalid cursor"}
+	}
+	return &cursor, nil
+}
+
+// likePattern matches term anywhere in a column, with LIKE wildcards in term taken literally
+func likePattern(term string) string {
+	return "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(term) + "%"
+}
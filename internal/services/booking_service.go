@@ -1,18 +1,155 @@
 package services
 
 import (
+	"errors"
 	"flyola-services/internal/models"
+	"fmt"
+	"math"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type BookingService struct {
-	db *gorm.DB
+	db         *gorm.DB
+	taxPercent float64
 }
 
-func NewBookingService(db *gorm.DB) *BookingService {
-	return &BookingService{db: db}
+func NewBookingService(db *gorm.DB, taxPercent float64) *BookingService {
+	return &BookingService{db: db, taxPercent: taxPercent}
+}
+
+// PriceBreakdown is the server-computed price of a hotel stay
+type PriceBreakdown struct {
+	NumberOfNights   int             `json:"number_of_nights"`
+	Nights           []NightlyRate   `json:"nights"`
+	RoomPrice        float64         `json:"room_price"`
+	Adults           int             `json:"adults"`
+	ExtraPersons     int             `json:"extra_persons"`
+	ExtraPersonPrice float64         `json:"extra_person_price"`
+	ExtraPersonTotal float64         `json:"extra_person_total"`
+	Children         []ChildCharge   `json:"children,omitempty"`
+	ChildAmount      float64         `json:"child_amount"`
+	MealPlan         *MealPlanCharge `json:"meal_plan,omitempty"`
+	MealPlanAmount   float64         `json:"meal_plan_amount"`
+	TotalAmount      float64         `json:"total_amount"`
+	TaxAmount        float64         `json:"tax_amount"`
+	DiscountAmount   float64         `json:"discount_amount"`
+	FinalAmount      float64         `json:"final_amount"`
+}
+
+// NightlyRate is the room rate charged for a single night of a stay
+type NightlyRate struct {
+	Date   string  `json:"date"`
+	Price  float64 `json:"price"`
+	Source string  `json:"source,omitempty"`
+}
+
+// MealPlanCharge is the meal plan line of a price breakdown. Nights holds the per person rate.
+type MealPlanCharge struct {
+	MealPlanID uint          `json:"meal_plan_id"`
+	Code       string        `json:"code"`
+	Name       string        `json:"name"`
+	Persons    int           `json:"persons"`
+	Nights     []NightlyRate `json:"nights"`
+	Total      float64       `json:"total"`
+}
+
+// BookingValidationError is returned when a booking request cannot be accepted as sent
+type BookingValidationError struct {
+	Message string
+}
+
+func (e *BookingValidationError) Error() string {
+	return e.Message
+}
+
+// PriceMismatchError is returned when client-supplied totals disagree with the computed breakdown
+type PriceMismatchError struct {
+	Field     string
+	Sent      float64
+	Breakdown *PriceBreakdown
+}
+
+func (e *PriceMismatchError) Error() string {
+	return fmt.Sprintf("%s %.2f does not match the computed price", e.Field, e.Sent)
+}
+
+// BookingConflictError is returned when a stay overlaps another booking or a blocked night
+type BookingConflictError struct {
+	RoomID uint
+	Dates  []string
+}
+
+func (e *BookingConflictError) Error() string {
+	return fmt.Sprintf("room %d is not available on %s", e.RoomID, strings.Join(e.Dates, ", "))
+}
+
+// InvalidTransitionError is returned when a status change is not allowed by the booking lifecycle
+type InvalidTransitionError struct {
+	From string
+	To   string
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("booking cannot move from %s to %s", e.From, e.To)
+}
+
+// BookingModification is a requested change to an existing booking. Nil fields keep their current value.
+type BookingModification struct {
+	RoomID         *uint      `json:"room_id"`
+	CheckInDate    *time.Time `json:"check_in_date"`
+	CheckOutDate   *time.Time `json:"check_out_date"`
+	NumberOfGuests *int       `json:"number_of_guests"`
+	ChildAges      []int      `json:"child_ages"`
+	MealPlanID     *uint      `json:"meal_plan_id"`
+}
+
+// Settlement actions returned with a booking modification
+const (
+	SettlementNone   = "none"
+	SettlementCharge = "charge"
+	SettlementRefund = "refund"
+)
+
+// ModificationResult is a modified booking together with what is owed because of the change.
+// A positive PriceDifference is a top-up to charge, a negative one an amount to refund.
+type ModificationResult struct {
+	Booking         *models.HotelBooking `json:"booking"`
+	Breakdown       *PriceBreakdown      `json:"breakdown"`
+	PreviousAmount  float64              `json:"previous_amount"`
+	NewAmount       float64              `json:"new_amount"`
+	PriceDifference float64              `json:"price_difference"`
+	Settlement      string               `json:"settlement"`
+}
+
+// Actors recorded in the booking status history for changes not made through a request
+const (
+	ActorSystem         = "system"
+	ActorPaymentGateway = "payment_gateway"
+)
+
+// maxReferenceAttempts bounds how often a booking insert is retried after a booking reference collision
+const maxReferenceAttempts = 5
+
+// inventoryHoldingStatuses are the booking statuses that keep their room nights reserved
+var inventoryHoldingStatuses = []string{
+	models.BookingStatusPending,
+	models.BookingStatusConfirmed,
+	models.BookingStatusCheckedIn,
+}
+
+// protectedFields can only change through the booking flow, never through a generic update
+var protectedFields = []string{
+	"order_id", "hotel_id", "room_id", "room_category_id", "inventory_type", "check_in_date", "check_out_date",
+	"number_of_nights", "number_of_guests", "child_ages", "room_price", "extra_persons", "extra_person_price",
+	"meal_plan_id", "meal_plan_amount", "child_amount", "total_amount", "tax_amount", "discount_amount", "final_amount",
+	"booking_status", "payment_status", "razorpay_order_id", "razorpay_order_amount", "cancellation_rules",
+	"booking_reference", "payment_id", "payment_method", "checked_in_at", "checked_out_at", "incidentals_amount",
+	"folio_closed_at", "deleted_at",
+	clause.Associations,
 }
 
 func (s *BookingService) GetAllBookings() ([]models.HotelBooking, error) {
@@ -30,8 +167,410 @@ func (s *BookingService) GetBookingByID(id uint) (*models.HotelBooking, error) {
 	return &booking, nil
 }
 
-func (s *BookingService) CreateBooking(booking *models.HotelBooking) error {
-	return s.db.Create(booking).Error
+// CreateBooking prices the stay server-side and saves the booking. Any totals sent by
+// the client must agree with the computed breakdown, otherwise the booking is rejected.
+func (s *BookingService) CreateBooking(booking *models.HotelBooking, actor string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return s.createBooking(tx, booking, actor)
+	})
+}
+
+// createBooking prices, reserves and saves a booking inside the caller's transaction
+func (s *BookingService) createBooking(tx *gorm.DB, booking *models.HotelBooking, actor string) error {
+	guests := booking.Guests
+	if booking.NumberOfGuests <= 0 && len(guests) > 0 {
+		booking.NumberOfGuests = len(guests)
+	}
+	if len(booking.GetChildAges()) == 0 {
+		// Without explicit child ages, the ages recorded on the guests decide who is a child
+		var ages []int
+		for _, guest := range guests {
+			if guest.Age != nil {
+				ages = append(ages, *guest.Age)
+			}
+		}
+		if len(ages) > 0 {
+			if err := booking.SetChildAges(ages); err != nil {
+				return err
+			}
+		}
+	}
+	setInventoryType(booking)
+
+	breakdown, err := s.priceBooking(tx, booking)
+	if err != nil {
+		return err
+	}
+
+	if err := validateBookingGuests(guests, booking.NumberOfGuests); err != nil {
+		return err
+	}
+
+	if err := checkClientTotals(booking, breakdown); err != nil {
+		return err
+	}
+	applyBreakdown(booking, breakdown)
+
+	booking.CancellationRules, err = cancellationPolicySnapshot(tx, "hotel_id", booking.HotelID)
+	if err != nil {
+		return err
+	}
+
+	// Every booking starts unpaid; confirmation only happens through payment verification
+	booking.BookingStatus = models.BookingStatusPending
+	booking.PaymentStatus = models.PaymentStatusPending
+	booking.RazorpayOrderID, booking.RazorpayOrderAmount = "", 0
+
+	// Nights held during checkout are handed back and reserved again by the booking, all under
+	// the same locks, so nobody else can take them in between
+	var hold *models.InventoryHold
+	if booking.HoldToken != "" {
+		if hold, err = takeStayHold(tx, booking); err != nil {
+			return err
+		}
+	}
+	if err := reserveBookingInventory(tx, booking); err != nil {
+		return err
+	}
+
+	if err := createWithReference(tx, booking); err != nil {
+		return err
+	}
+	if hold != nil {
+		if err := tx.Model(hold).Update("booking_id", booking.ID).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(guests) > 0 {
+		for i := range guests {
+			guests[i].ID = 0
+			guests[i].BookingID = booking.ID
+			guests[i].Booking = nil
+		}
+		if err := tx.Create(&guests).Error; err != nil {
+			return err
+		}
+		booking.Guests = guests
+	}
+
+	return recordStatusHistory(tx, booking, "", actor, "Booking created")
+}
+
+// createWithReference inserts a booking under a freshly generated reference, retrying with a new
+// reference if it collides with an existing one. References sent by the client are never used.
+func createWithReference(tx *gorm.DB, booking *models.HotelBooking) error {
+	for attempt := 1; ; attempt++ {
+		booking.BookingReference = ""
+		err := tx.Omit(clause.Associations).Create(booking).Error
+		if err == nil || !errors.Is(err, gorm.ErrDuplicatedKey) || attempt == maxReferenceAttempts {
+			return err
+		}
+	}
+}
+
+// QuoteBooking returns the price breakdown for a prospective booking without saving it
+func (s *BookingService) QuoteBooking(booking *models.HotelBooking) (*PriceBreakdown, error) {
+	setInventoryType(booking)
+	return s.priceBooking(s.db, booking)
+}
+
+// setInventoryType decides what a new booking is sold from: a booking naming a room holds that
+// room, one naming only a room category takes a room of the category's allotment
+func setInventoryType(booking *models.HotelBooking) {
+	if booking.RoomID != nil && *booking.RoomID == 0 {
+		booking.RoomID = nil
+	}
+	booking.InventoryType = models.InventoryTypeRoom
+	if booking.RoomID == nil && booking.RoomCategoryID != nil {
+		booking.InventoryType = models.InventoryTypeAllotment
+	}
+}
+
+// priceBooking normalises the stay dates and guest count on the booking and computes its price.
+// Nights with a RoomAvailability price, or an allotment price for category bookings, use it in
+// place of the room's own rates.
+func (s *BookingService) priceBooking(tx *gorm.DB, booking *models.HotelBooking) (*PriceBreakdown, error) {
+	if booking.CheckInDate.IsZero() || booking.CheckOutDate.IsZero() {
+		return nil, &BookingValidationError{Message: "check_in_date and check_out_date are required"}
+	}
+	checkIn := dateOnly(booking.CheckInDate)
+	checkOut := dateOnly(booking.CheckOutDate)
+	if !checkOut.After(checkIn) {
+		return nil, &BookingValidationError{Message: "check_out_date must be after check_in_date"}
+	}
+	if checkIn.Before(dateOnly(time.Now())) {
+		return nil, &BookingValidationError{Message: "check_in_date cannot be in the past"}
+	}
+	if booking.NumberOfGuests <= 0 {
+		booking.NumberOfGuests = 1
+	}
+
+	room, overrides, err := bookingRateRoom(tx, booking, checkIn, checkOut)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkStayRestrictions(tx, room.HotelID, room.RoomCategoryID, checkIn, checkOut); err != nil {
+		return nil, err
+	}
+
+	policy, err := findChildPolicy(tx, room.HotelID)
+	if err != nil {
+		return nil, err
+	}
+	party, err := splitParty(policy, booking.NumberOfGuests, booking.GetChildAges())
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := loadRatePlanRules(tx, []uint{room.HotelID}, checkIn, checkOut)
+	if err != nil {
+		return nil, err
+	}
+
+	nights := stayNights(checkIn, checkOut)
+	breakdown, err := s.roomBreakdown(room, party, nights, overrides, rules)
+	if err != nil {
+		return nil, err
+	}
+
+	if booking.MealPlanID != nil {
+		breakdown.MealPlan, err = priceMealPlan(tx, *booking.MealPlanID, &room.RoomCategory, booking.NumberOfGuests, nights)
+		if err != nil {
+			return nil, err
+		}
+		breakdown.MealPlanAmount = breakdown.MealPlan.Total
+		s.applyTotals(breakdown)
+	}
+
+	booking.ChildAges = nil
+	if len(party.Children) > 0 {
+		if err := booking.SetChildAges(party.childAges()); err != nil {
+			return nil, err
+		}
+	}
+	roomCategoryID := room.RoomCategoryID
+	booking.HotelID = room.HotelID
+	booking.RoomCategoryID = &roomCategoryID
+	booking.CheckInDate = checkIn
+	booking.CheckOutDate = checkOut
+
+	return breakdown, nil
+}
+
+// bookingRateRoom loads the room whose rates price a booking, with the nightly price overrides
+// that apply to it. Room bookings use their own room; category bookings use the category's rate
+// room and allotment prices, so assigning them a room never changes their price.
+func bookingRateRoom(tx *gorm.DB, booking *models.HotelBooking, checkIn, checkOut time.Time) (*models.Room, map[string]float64, error) {
+	if booking.InventoryType == models.InventoryTypeAllotment {
+		if booking.HotelID == 0 || booking.RoomCategoryID == nil {
+			return nil, nil, &BookingValidationError{Message: "hotel_id and room_category_id are required to book a room category"}
+		}
+		room, err := allotmentRateRoom(tx, booking.HotelID, *booking.RoomCategoryID)
+		if err != nil {
+			return nil, nil, err
+		}
+		overrides, err := allotmentPriceOverrides(tx, room.HotelID, room.RoomCategoryID, checkIn, checkOut)
+		if err != nil {
+			return nil, nil, err
+		}
+		return room, overrides, nil
+	}
+
+	if booking.RoomID == nil {
+		return nil, nil, &BookingValidationError{Message: "room_id or room_category_id is required"}
+	}
+	var room models.Room
+	if err := tx.Preload("RoomCategory").First(&room, *booking.RoomID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, &BookingValidationError{Message: "Room not found"}
+		}
+		return nil, nil, err
+	}
+	overrides, err := nightlyPriceOverrides(tx, room.ID, checkIn, checkOut)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &room, overrides, nil
+}
+
+// roomBreakdown prices a stay in a room for a party. Each night uses its override from overrides
+// when there is one, otherwise the room's occupancy rate for the adults as adjusted by the hotel's
+// rate plan rules; children pay their age band's nightly price. The room's category must be loaded.
+func (s *BookingService) roomBreakdown(room *models.Room, party *stayParty, nights []time.Time, overrides map[string]float64, rules *ratePlanRules) (*PriceBreakdown, error) {
+	if maxOccupancy := room.RoomCategory.MaxOccupancy; maxOccupancy > 0 && party.Occupancy > maxOccupancy {
+		return nil, &BookingValidationError{
+			Message: fmt.Sprintf("%s rooms allow at most %d guest(s), %d requested", room.RoomCategory.Name, maxOccupancy, party.Occupancy),
+		}
+	}
+
+	extraPersons := party.Adults - 2
+	if extraPersons < 0 {
+		extraPersons = 0
+	}
+	if extraPersons > room.MaxExtraPersons {
+		return nil, &BookingValidationError{
+			Message: fmt.Sprintf("Room allows at most %d extra person(s), %d requested", room.MaxExtraPersons, extraPersons),
+		}
+	}
+
+	rate := occupancyRate(room, party.Adults)
+	breakdown := &PriceBreakdown{
+		Adults:           party.Adults,
+		ExtraPersons:     extraPersons,
+		ExtraPersonPrice: room.ExtraPersonPrice,
+	}
+	for _, night := range nights {
+		nightly := nightlyRoomRate(room, night, rate, overrides, rules)
+		breakdown.Nights = append(breakdown.Nights, nightly)
+		breakdown.RoomPrice += nightly.Price
+	}
+	if breakdown.RoomPrice <= 0 {
+		return nil, &BookingValidationError{Message: "Room has no price configured for the selected dates"}
+	}
+
+	breakdown.NumberOfNights = len(breakdown.Nights)
+	breakdown.RoomPrice = roundAmount(breakdown.RoomPrice)
+	breakdown.ExtraPersonTotal = roundAmount(room.ExtraPersonPrice * float64(extraPersons) * float64(breakdown.NumberOfNights))
+	for _, child := range party.Children {
+		child.Total = roundAmount(child.NightlyPrice * float64(breakdown.NumberOfNights))
+		breakdown.Children = append(breakdown.Children, child)
+		breakdown.ChildAmount += child.Total
+	}
+	breakdown.ChildAmount = roundAmount(breakdown.ChildAmount)
+	s.applyTotals(breakdown)
+	return breakdown, nil
+}
+
+// occupancyRate is the room's single or double occupancy price for a number of guests, falling
+// back to its base price
+func occupancyRate(room *models.Room, guests int) float64 {
+	if guests == 1 && room.SinglePrice > 0 {
+		return room.SinglePrice
+	}
+	if guests >= 2 && room.DoublePrice > 0 {
+		return room.DoublePrice
+	}
+	return room.BasePrice
+}
+
+// applyTotals works out the total, tax and final amount from the lines of a breakdown
+func (s *BookingService) applyTotals(breakdown *PriceBreakdown) {
+	breakdown.TotalAmount = roundAmount(breakdown.RoomPrice + breakdown.ExtraPersonTotal + breakdown.ChildAmount + breakdown.MealPlanAmount)
+	breakdown.TaxAmount = roundAmount(breakdown.TotalAmount * s.taxPercent / 100)
+	breakdown.FinalAmount = roundAmount(breakdown.TotalAmount + breakdown.TaxAmount - breakdown.DiscountAmount)
+}
+
+// validateBookingGuests checks the guest list sent with a new booking. Guests are optional, but
+// when sent there must be one per guest on the booking and exactly one of them the main guest.
+func validateBookingGuests(guests []models.HotelGuest, numberOfGuests int) error {
+	if len(guests) == 0 {
+		return nil
+	}
+	if len(guests) != numberOfGuests {
+		return &BookingValidationError{
+			Message: fmt.Sprintf("%d guest(s) provided for a booking of %d guest(s)", len(guests), numberOfGuests),
+		}
+	}
+
+	mainGuests := 0
+	for _, guest := range guests {
+		if strings.TrimSpace(guest.Name) == "" {
+			return &BookingValidationError{Message: "Every guest needs a name"}
+		}
+		if guest.IsMainGuest {
+			mainGuests++
+		}
+	}
+	if mainGuests != 1 {
+		return &BookingValidationError{Message: "Exactly one guest must be the main guest"}
+	}
+
+	return nil
+}
+
+// checkClientTotals rejects a booking whose client-supplied amounts disagree with the breakdown.
+// Amounts the client left out (zero) are simply filled in from the breakdown.
+func checkClientTotals(booking *models.HotelBooking, breakdown *PriceBreakdown) error {
+	sent := []struct {
+		field    string
+		sent     float64
+		computed float64
+	}{
+		{"room_price", booking.RoomPrice, breakdown.RoomPrice},
+		{"meal_plan_amount", booking.MealPlanAmount, breakdown.MealPlanAmount},
+		{"child_amount", booking.ChildAmount, breakdown.ChildAmount},
+		{"total_amount", booking.TotalAmount, breakdown.TotalAmount},
+		{"tax_amount", booking.TaxAmount, breakdown.TaxAmount},
+		{"discount_amount", booking.DiscountAmount, breakdown.DiscountAmount},
+		{"final_amount", booking.FinalAmount, breakdown.FinalAmount},
+	}
+
+	for _, amount := range sent {
+		if amount.sent != 0 && math.Abs(amount.sent-amount.computed) > 0.01 {
+			return &PriceMismatchError{Field: amount.field, Sent: amount.sent, Breakdown: breakdown}
+		}
+	}
+	return nil
+}
+
+func applyBreakdown(booking *models.HotelBooking, breakdown *PriceBreakdown) {
+	booking.NumberOfNights = breakdown.NumberOfNights
+	booking.RoomPrice = breakdown.RoomPrice
+	booking.ExtraPersons = breakdown.ExtraPersons
+	booking.ExtraPersonPrice = breakdown.ExtraPersonPrice
+	booking.MealPlanAmount = breakdown.MealPlanAmount
+	booking.ChildAmount = breakdown.ChildAmount
+	booking.TotalAmount = breakdown.TotalAmount
+	booking.TaxAmount = breakdown.TaxAmount
+	booking.DiscountAmount = breakdown.DiscountAmount
+	booking.FinalAmount = breakdown.FinalAmount
+}
+
+// nightlyPriceOverrides returns the manual RoomAvailability prices set for a room, keyed by date
+func nightlyPriceOverrides(tx *gorm.DB, roomID uint, checkIn, checkOut time.Time) (map[string]float64, error) {
+	overrides, err := roomPriceOverrides(tx, []uint{roomID}, checkIn, checkOut)
+	if err != nil {
+		return nil, err
+	}
+	return overrides[roomID], nil
+}
+
+// roomPriceOverrides returns the manual RoomAvailability prices set for several rooms, keyed by room and date
+func roomPriceOverrides(tx *gorm.DB, roomIDs []uint, checkIn, checkOut time.Time) (map[uint]map[string]float64, error) {
+	var availabilities []models.RoomAvailability
+	if err := tx.Where("room_id IN ? AND date >= ? AND date < ? AND price > 0 AND price_source <> ?",
+		roomIDs, checkIn, checkOut, models.PriceSourceBase).
+		Find(&availabilities).Error; err != nil {
+		return nil, err
+	}
+
+	overrides := make(map[uint]map[string]float64, len(roomIDs))
+	for _, availability := range availabilities {
+		if overrides[availability.RoomID] == nil {
+			overrides[availability.RoomID] = make(map[string]float64)
+		}
+		overrides[availability.RoomID][availability.Date.Format("2006-01-02")] = availability.Price
+	}
+	return overrides, nil
+}
+
+// stayNights returns the date of every night between check-in and check-out
+func stayNights(checkIn, checkOut time.Time) []time.Time {
+	var nights []time.Time
+	for night := checkIn; night.Before(checkOut); night = night.AddDate(0, 0, 1) {
+		nights = append(nights, night)
+	}
+	return nights
+}
+
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
 }
 
 func (s *BookingService) UpdateBooking(id uint, updates *models.HotelBooking) (*models.HotelBooking, error) {
@@ -40,7 +579,7 @@ func (s *BookingService) UpdateBooking(id uint, updates *models.HotelBooking) (*
 		return nil, err
 	}
 
-	if err := s.db.Model(&booking).Updates(updates).Error; err != nil {
+	if err := s.db.Model(&booking).Omit(protectedFields...).Updates(updates).Error; err != nil {
 		return nil, err
 	}
 
@@ -52,22 +591,686 @@ func (s *BookingService) UpdateBooking(id uint, updates *models.HotelBooking) (*
 	return &booking, nil
 }
 
+// ModifyBooking changes the room, dates, guest count or meal plan of a pending or confirmed booking. The old
+// nights are released and the new ones reserved in the same transaction, so a failed modification
+// leaves the original booking untouched. The stay is repriced and the difference returned.
+func (s *BookingService) ModifyBooking(id uint, modification *BookingModification, actor string) (*ModificationResult, error) {
+	var result ModificationResult
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockBookingOrder(tx, id); err != nil {
+			return err
+		}
+		var booking models.HotelBooking
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, id).Error; err != nil {
+			return err
+		}
+
+		if booking.BookingStatus != models.BookingStatusPending && booking.BookingStatus != models.BookingStatusConfirmed {
+			return &BookingValidationError{
+				Message: fmt.Sprintf("A %s booking cannot be modified", booking.BookingStatus),
+			}
+		}
+
+		modified := booking
+		if modification.RoomID != nil {
+			if booking.InventoryType == models.InventoryTypeAllotment {
+				return &BookingValidationError{Message: "Rooms of a category booking are assigned through /bookings/:id/assign-room"}
+			}
+			roomID := *modification.RoomID
+			modified.RoomID = &roomID
+		}
+		if modification.CheckInDate != nil {
+			modified.CheckInDate = *modification.CheckInDate
+		}
+		if modification.CheckOutDate != nil {
+			modified.CheckOutDate = *modification.CheckOutDate
+		}
+		if modification.MealPlanID != nil {
+			// A meal plan ID of 0 drops the meal plan
+			modified.MealPlanID = modification.MealPlanID
+			if *modification.MealPlanID == 0 {
+				modified.MealPlanID = nil
+			}
+		}
+		if modification.NumberOfGuests != nil {
+			if *modification.NumberOfGuests <= 0 {
+				return &BookingValidationError{Message: "number_of_guests must be at least 1"}
+			}
+			modified.NumberOfGuests = *modification.NumberOfGuests
+		}
+		if modification.ChildAges != nil {
+			if err := modified.SetChildAges(modification.ChildAges); err != nil {
+				return err
+			}
+		}
+
+		breakdown, err := s.priceBooking(tx, &modified)
+		if err != nil {
+			return err
+		}
+		if modified.HotelID != booking.HotelID {
+			return &BookingValidationError{Message: "A booking can only be moved to another room of the same hotel"}
+		}
+
+		var recordedGuests int64
+		if err := tx.Model(&models.HotelGuest{}).Where("booking_id = ?", booking.ID).Count(&recordedGuests).Error; err != nil {
+			return err
+		}
+		if recordedGuests > int64(modified.NumberOfGuests) {
+			return &BookingValidationError{
+				Message: fmt.Sprintf("Booking has %d guest(s) recorded; remove guests before reducing the guest count", recordedGuests),
+			}
+		}
+
+		if err := releaseBookingInventory(tx, &booking); err != nil {
+			return err
+		}
+		if err := reserveBookingInventory(tx, &modified); err != nil {
+			return err
+		}
+
+		applyBreakdown(&modified, breakdown)
+		if err := tx.Model(&models.HotelBooking{}).Where("id = ?", booking.ID).Updates(map[string]interface{}{
+			"hotel_id":           modified.HotelID,
+			"room_id":            modified.RoomID,
+			"room_category_id":   modified.RoomCategoryID,
+			"check_in_date":      modified.CheckInDate,
+			"check_out_date":     modified.CheckOutDate,
+			"number_of_nights":   modified.NumberOfNights,
+			"number_of_guests":   modified.NumberOfGuests,
+			"child_ages":         modified.ChildAges,
+			"room_price":         modified.RoomPrice,
+			"extra_persons":      modified.ExtraPersons,
+			"extra_person_price": modified.ExtraPersonPrice,
+			"meal_plan_id":       modified.MealPlanID,
+			"meal_plan_amount":   modified.MealPlanAmount,
+			"child_amount":       modified.ChildAmount,
+			"total_amount":       modified.TotalAmount,
+			"tax_amount":         modified.TaxAmount,
+			"discount_amount":    modified.DiscountAmount,
+			"final_amount":       modified.FinalAmount,
+		}).Error; err != nil {
+			return err
+		}
+		if err := syncOrderTotals(tx, booking.OrderID); err != nil {
+			return err
+		}
+
+		result.Breakdown = breakdown
+		result.PreviousAmount = booking.FinalAmount
+		result.NewAmount = modified.FinalAmount
+		result.PriceDifference = roundAmount(modified.FinalAmount - booking.FinalAmount)
+		result.Settlement = settlementFor(&booking, result.PriceDifference)
+
+		reason := fmt.Sprintf("Booking modified: %s, %s to %s, %d guest(s), amount %.2f -> %.2f",
+			bookedInventory(&modified), modified.CheckInDate.Format("2006-01-02"), modified.CheckOutDate.Format("2006-01-02"),
+			modified.NumberOfGuests, booking.FinalAmount, modified.FinalAmount)
+		return recordStatusHistory(tx, &booking, booking.BookingStatus, actor, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.Booking, err = s.GetBookingByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// settlementFor tells the client how to settle a price difference. Unpaid bookings simply pay the
+// new amount, so only paid bookings need a top-up charge or a partial refund.
+func settlementFor(booking *models.HotelBooking, difference float64) string {
+	if booking.PaymentStatus != models.PaymentStatusPaid || math.Abs(difference) < 0.01 {
+		return SettlementNone
+	}
+	if difference > 0 {
+		return SettlementCharge
+	}
+	return SettlementRefund
+}
+
+// AssignRoom gives a category booking its room. The room must be of the booked category in the
+// booking's hotel and free for the whole stay; a room assigned earlier is released. The price is
+// unchanged since category bookings are priced from the category.
+func (s *BookingService) AssignRoom(id, roomID uint, actor string) (*models.HotelBooking, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var booking models.HotelBooking
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, id).Error; err != nil {
+			return err
+		}
+
+		if booking.InventoryType != models.InventoryTypeAllotment {
+			return &BookingValidationError{Message: "Only category bookings get a room assigned; use modify to move a room booking"}
+		}
+		if booking.BookingStatus != models.BookingStatusPending && booking.BookingStatus != models.BookingStatusConfirmed {
+			return &BookingValidationError{
+				Message: fmt.Sprintf("A room cannot be assigned to a %s booking", booking.BookingStatus),
+			}
+		}
+
+		if booking.RoomID != nil && *booking.RoomID == roomID {
+			return nil
+		}
+		if err := assignRoom(tx, &booking, roomID); err != nil {
+			return err
+		}
+		return recordStatusHistory(tx, &booking, booking.BookingStatus, actor, fmt.Sprintf("Room %d assigned", roomID))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetBookingByID(id)
+}
+
+// assignRoom moves a locked category booking onto a room of its category, releasing the room it
+// had before. The room must be active and free for the whole stay.
+func assignRoom(tx *gorm.DB, booking *models.HotelBooking, roomID uint) error {
+	var room models.Room
+	if err := tx.Select("id", "hotel_id", "room_category_id", "status").First(&room, roomID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &BookingValidationError{Message: "Room not found"}
+		}
+		return err
+	}
+	if room.HotelID != booking.HotelID || room.RoomCategoryID != *booking.RoomCategoryID {
+		return &BookingValidationError{Message: "The room must be of the booked category in the booking's hotel"}
+	}
+	if room.Status != 0 {
+		return &BookingValidationError{Message: "The room is not active"}
+	}
+
+	if booking.RoomID != nil {
+		if err := releaseRoomNights(tx, *booking.RoomID, booking.CheckInDate, booking.CheckOutDate); err != nil {
+			return err
+		}
+	}
+	if err := reserveRoomNights(tx, roomID, dateOnly(booking.CheckInDate), dateOnly(booking.CheckOutDate), booking.ID); err != nil {
+		return err
+	}
+
+	if err := tx.Model(&models.HotelBooking{}).Where("id = ?", booking.ID).Update("room_id", roomID).Error; err != nil {
+		return err
+	}
+	booking.RoomID = &roomID
+	return nil
+}
+
+// DeleteBooking removes a booking and frees the room nights it was holding
 func (s *BookingService) DeleteBooking(id uint) error {
-	return s.db.Delete(&models.HotelBooking{}, id).Error
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockBookingOrder(tx, id); err != nil {
+			return err
+		}
+		var booking models.HotelBooking
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, id).Error; err != nil {
+			return err
+		}
+
+		if err := releaseBookingInventory(tx, &booking); err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&models.HotelBooking{}, id).Error; err != nil {
+			return err
+		}
+		return syncOrderTotals(tx, booking.OrderID)
+	})
+}
+
+// RestoreBooking brings back a deleted booking. A booking that was still holding its nights takes
+// them again, and fails with a conflict if they have been sold in the meantime.
+func (s *BookingService) RestoreBooking(id uint) (*models.HotelBooking, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockBookingOrder(tx, id); err != nil {
+			return err
+		}
+		var booking models.HotelBooking
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, id).Error; err != nil {
+			return err
+		}
+		if !booking.DeletedAt.Valid {
+			return &BookingValidationError{Message: "Booking is not deleted"}
+		}
+
+		if holdsInventory(booking.BookingStatus) {
+			if err := reserveBookingInventory(tx, &booking); err != nil {
+				return err
+			}
+		}
+		if err := tx.Unscoped().Model(&booking).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return syncOrderTotals(tx, booking.OrderID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetBookingByID(id)
+}
+
+// CancelBooking cancels a booking, frees the room nights it was holding and, if it was paid,
+// records the refund due under the cancellation rules the booking was made with
+func (s *BookingService) CancelBooking(id uint, actor, reason string) (*models.HotelBooking, *CancellationQuote, error) {
+	if reason == "" {
+		reason = "Booking cancelled"
+	}
+
+	var quote *CancellationQuote
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockBookingOrder(tx, id); err != nil {
+			return err
+		}
+		var booking models.HotelBooking
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, id).Error; err != nil {
+			return err
+		}
+
+		var err error
+		quote, err = cancelBooking(tx, &booking, actor, reason)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	booking, err := s.GetBookingByID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return booking, quote, nil
 }
 
-func (s *BookingService) CancelBooking(id uint) (*models.HotelBooking, error) {
+// cancelBooking cancels a locked booking and records its refund
+func cancelBooking(tx *gorm.DB, booking *models.HotelBooking, actor, reason string) (*CancellationQuote, error) {
+	quote, err := quoteBookingCancellation(tx, booking, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := transitionBooking(tx, booking, models.BookingStatusCancelled, actor, reason); err != nil {
+		return nil, err
+	}
+	if err := recordRefund(tx, booking, quote.RefundAmount); err != nil {
+		return nil, err
+	}
+	if err := syncOrderTotals(tx, booking.OrderID); err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
+
+// PreviewCancellation returns the refund a booking would get if it were cancelled now
+func (s *BookingService) PreviewCancellation(id uint) (*CancellationQuote, error) {
 	var booking models.HotelBooking
 	if err := s.db.First(&booking, id).Error; err != nil {
 		return nil, err
 	}
 
-	booking.BookingStatus = "cancelled"
-	if err := s.db.Save(&booking).Error; err != nil {
+	if !models.CanTransitionBookingStatus(booking.BookingStatus, models.BookingStatusCancelled) {
+		return nil, &InvalidTransitionError{From: booking.BookingStatus, To: models.BookingStatusCancelled}
+	}
+	return quoteBookingCancellation(s.db, &booking, time.Now())
+}
+
+func quoteBookingCancellation(tx *gorm.DB, booking *models.HotelBooking, now time.Time) (*CancellationQuote, error) {
+	var hotel models.Hotel
+	if err := tx.Select("id", "check_in_time").First(&hotel, booking.HotelID).Error; err != nil {
 		return nil, err
 	}
 
-	return &booking, nil
+	paid := 0.0
+	if booking.PaymentStatus == models.PaymentStatusPaid {
+		paid = booking.FinalAmount
+	}
+	return quoteCancellation(booking.CancellationRules, checkInTime(booking.CheckInDate, hotel.CheckInTime), paid, now), nil
+}
+
+// recordRefund writes the refund of a cancelled booking to its payment. Bookings paid before
+// payments were recorded get their payment row created from the booking.
+func recordRefund(tx *gorm.DB, booking *models.HotelBooking, refundAmount float64) error {
+	if booking.PaymentStatus != models.PaymentStatusPaid {
+		return nil
+	}
+
+	var payment models.HotelPayment
+	err := tx.Where("booking_id = ?", booking.ID).Order("id DESC").First(&payment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		payment = newHotelPayment(booking)
+		err = tx.Omit(clause.Associations).Create(&payment).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := tx.Model(&models.HotelPayment{}).Where("id = ?", payment.ID).Updates(map[string]interface{}{
+		"refund_amount": refundAmount,
+		"refund_date":   &now,
+		"status":        models.PaymentStatusRefunded,
+	}).Error; err != nil {
+		return err
+	}
+
+	booking.PaymentStatus = models.PaymentStatusRefunded
+	return tx.Model(&models.HotelBooking{}).Where("id = ?", booking.ID).
+		Update("payment_status", booking.PaymentStatus).Error
+}
+
+func newHotelPayment(booking *models.HotelBooking) models.HotelPayment {
+	paidAt := time.Now()
+	return models.HotelPayment{
+		BookingID:     booking.ID,
+		PaymentMethod: booking.PaymentMethod,
+		TransactionID: booking.PaymentID,
+		Amount:        booking.FinalAmount,
+		Status:        models.PaymentStatusPaid,
+		PaymentDate:   &paidAt,
+	}
+}
+
+// TransitionBooking moves a booking to a new lifecycle status, recording who made the change and why
+func (s *BookingService) TransitionBooking(id uint, to, actor, reason string) (*models.HotelBooking, error) {
+	var booking models.HotelBooking
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockBookingOrder(tx, id); err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, id).Error; err != nil {
+			return err
+		}
+		if err := transitionBooking(tx, &booking, to, actor, reason); err != nil {
+			return err
+		}
+		return syncOrderTotals(tx, booking.OrderID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetBookingByID(id)
+}
+
+// ConfirmPayment marks a pending booking as paid and confirmed after the gateway verified the payment.
+// Verifying the same payment twice returns the booking unchanged.
+func (s *BookingService) ConfirmPayment(id uint, razorpayOrderID, paymentID, paymentMethod string) (*models.HotelBooking, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var booking models.HotelBooking
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, id).Error; err != nil {
+			return err
+		}
+
+		// The Razorpay order must be the one created for the booking, for what the booking costs now
+		if booking.RazorpayOrderID == "" || booking.RazorpayOrderID != razorpayOrderID {
+			return ErrBookingPaymentMismatch
+		}
+		if booking.PaymentStatus == models.PaymentStatusPaid && booking.PaymentID == paymentID {
+			return nil
+		}
+		if booking.RazorpayOrderAmount != amountInPaise(booking.FinalAmount) {
+			return ErrBookingPaymentMismatch
+		}
+
+		return confirmBookingPayment(tx, &booking, paymentID, paymentMethod)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetBookingByID(id)
+}
+
+// PayableBooking returns an unpaid booking with the amount in paise its Razorpay order must be
+// created for
+func (s *BookingService) PayableBooking(id uint) (*models.HotelBooking, int64, error) {
+	booking, err := s.GetBookingByID(id)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := checkPayable(booking); err != nil {
+		return nil, 0, err
+	}
+	return booking, amountInPaise(booking.FinalAmount), nil
+}
+
+// AttachPaymentOrder stores the Razorpay order the guest will pay an unpaid booking with
+func (s *BookingService) AttachPaymentOrder(id uint, razorpayOrderID string, amount int64) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var booking models.HotelBooking
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, id).Error; err != nil {
+			return err
+		}
+		if err := checkPayable(&booking); err != nil {
+			return err
+		}
+
+		return tx.Model(&models.HotelBooking{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"razorpay_order_id":     razorpayOrderID,
+			"razorpay_order_amount": amount,
+		}).Error
+	})
+}
+
+// checkPayable tells whether a booking can be paid on its own
+func checkPayable(booking *models.HotelBooking) error {
+	if booking.OrderID != nil {
+		return &BookingValidationError{Message: "Rooms of an order are paid through the order"}
+	}
+	if booking.PaymentStatus != models.PaymentStatusPending || booking.BookingStatus != models.BookingStatusPending {
+		return &BookingValidationError{
+			Message: fmt.Sprintf("A %s booking with payment %s cannot be paid", booking.BookingStatus, booking.PaymentStatus),
+		}
+	}
+	return nil
+}
+
+// amountInPaise converts an amount in rupees to the paise Razorpay orders are created in
+func amountInPaise(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// confirmBookingPayment records a verified payment on a locked booking and confirms it. A booking
+// already paid with the same payment is left unchanged.
+func confirmBookingPayment(tx *gorm.DB, booking *models.HotelBooking, paymentID, paymentMethod string) error {
+	if booking.PaymentStatus == models.PaymentStatusPaid && booking.PaymentID == paymentID {
+		return nil
+	}
+
+	booking.PaymentStatus = models.PaymentStatusPaid
+	booking.PaymentID = paymentID
+	booking.PaymentMethod = paymentMethod
+	if err := tx.Model(&models.HotelBooking{}).Where("id = ?", booking.ID).Updates(map[string]interface{}{
+		"payment_status": booking.PaymentStatus,
+		"payment_id":     booking.PaymentID,
+		"payment_method": booking.PaymentMethod,
+	}).Error; err != nil {
+		return err
+	}
+
+	payment := newHotelPayment(booking)
+	if err := tx.Omit(clause.Associations).Create(&payment).Error; err != nil {
+		return err
+	}
+
+	return transitionBooking(tx, booking, models.BookingStatusConfirmed, ActorPaymentGateway, "Payment "+paymentID+" verified")
+}
+
+// ExpirePendingBookings expires up to batchSize bookings that are still unpaid after ttl, frees
+// their rooms and brings the totals of their orders down to the rooms left. Orders and then
+// bookings are claimed with SKIP LOCKED, so several server replicas can run this at the same time
+// without expiring the same booking twice, and a room whose order is being paid is left for later.
+func (s *BookingService) ExpirePendingBookings(ttl time.Duration, batchSize int) (int, error) {
+	cutoff := time.Now().Add(-ttl)
+	reason := fmt.Sprintf("Payment not received within %s", ttl)
+	pending := func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("booking_status = ? AND payment_status = ? AND created_at < ?",
+			models.BookingStatusPending, models.PaymentStatusPending, cutoff)
+	}
+
+	expired := 0
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var candidates []models.HotelBooking
+		if err := pending(tx).Select("id", "order_id").Order("id").Limit(batchSize).Find(&candidates).Error; err != nil {
+			return err
+		}
+
+		// Orders are locked before their bookings, as everywhere else
+		var orderIDs []uint
+		for _, candidate := range candidates {
+			if candidate.OrderID != nil {
+				orderIDs = append(orderIDs, *candidate.OrderID)
+			}
+		}
+		claimedOrders := make(map[uint]bool)
+		if len(orderIDs) > 0 {
+			var orders []models.BookingOrder
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).Select("id").
+				Where("id IN ?", orderIDs).Find(&orders).Error; err != nil {
+				return err
+			}
+			for _, order := range orders {
+				claimedOrders[order.ID] = true
+			}
+		}
+		var ids []uint
+		for _, candidate := range candidates {
+			if candidate.OrderID == nil || claimedOrders[*candidate.OrderID] {
+				ids = append(ids, candidate.ID)
+			}
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		var bookings []models.HotelBooking
+		if err := pending(tx).Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("id IN ?", ids).Order("id").Find(&bookings).Error; err != nil {
+			return err
+		}
+
+		orders := make(map[uint]bool)
+		for i := range bookings {
+			if err := transitionBooking(tx, &bookings[i], models.BookingStatusExpired, ActorSystem, reason); err != nil {
+				return err
+			}
+			if bookings[i].OrderID != nil {
+				orders[*bookings[i].OrderID] = true
+			}
+			expired++
+		}
+		for orderID := range orders {
+			if err := syncOrderTotals(tx, &orderID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return expired, nil
+}
+
+// GetBookingHistory returns every status transition of a booking, oldest first
+func (s *BookingService) GetBookingHistory(id uint) ([]models.BookingStatusHistory, error) {
+	if err := s.db.Select("id").First(&models.HotelBooking{}, id).Error; err != nil {
+		return nil, err
+	}
+
+	var history []models.BookingStatusHistory
+	err := s.db.Where("booking_id = ?", id).Order("created_at ASC, id ASC").Find(&history).Error
+	return history, err
+}
+
+// transitionBooking validates and applies a status change on a locked booking, freeing its
+// room nights when it leaves the statuses that hold inventory, and records the transition
+func transitionBooking(tx *gorm.DB, booking *models.HotelBooking, to, actor, reason string) error {
+	from := booking.BookingStatus
+	if !models.CanTransitionBookingStatus(from, to) {
+		return &InvalidTransitionError{From: from, To: to}
+	}
+	if to == models.BookingStatusCheckedIn && booking.RoomID == nil {
+		return &BookingValidationError{Message: "Assign a room to the booking before checking the guest in"}
+	}
+
+	if !holdsInventory(to) {
+		if err := releaseBookingInventory(tx, booking); err != nil {
+			return err
+		}
+	}
+
+	booking.BookingStatus = to
+	if err := tx.Model(&models.HotelBooking{}).Where("id = ?", booking.ID).
+		Update("booking_status", to).Error; err != nil {
+		return err
+	}
+
+	return recordStatusHistory(tx, booking, from, actor, reason)
+}
+
+func recordStatusHistory(tx *gorm.DB, booking *models.HotelBooking, from, actor, reason string) error {
+	if actor == "" {
+		actor = ActorSystem
+	}
+	return tx.Create(&models.BookingStatusHistory{
+		BookingID:     booking.ID,
+		FromStatus:    from,
+		ToStatus:      booking.BookingStatus,
+		PaymentStatus: booking.PaymentStatus,
+		Actor:         actor,
+		Reason:        reason,
+	}).Error
+}
+
+// reserveBookingInventory reserves the nights covered by a booking: a room of the category's
+// allotment for category bookings, and the nights of the booking's room once it has one
+func reserveBookingInventory(tx *gorm.DB, booking *models.HotelBooking) error {
+	checkIn := dateOnly(booking.CheckInDate)
+	checkOut := dateOnly(booking.CheckOutDate)
+	if booking.InventoryType == models.InventoryTypeAllotment {
+		if err := reserveAllotmentNights(tx, booking.HotelID, *booking.RoomCategoryID, checkIn, checkOut); err != nil {
+			return err
+		}
+	}
+	if booking.RoomID == nil {
+		return nil
+	}
+	return reserveRoomNights(tx, *booking.RoomID, checkIn, checkOut, booking.ID)
+}
+
+// releaseBookingInventory frees the nights of a booking that is still holding them
+func releaseBookingInventory(tx *gorm.DB, booking *models.HotelBooking) error {
+	if !holdsInventory(booking.BookingStatus) {
+		return nil
+	}
+	if booking.InventoryType == models.InventoryTypeAllotment && booking.RoomCategoryID != nil {
+		if err := releaseAllotmentNights(tx, booking.HotelID, *booking.RoomCategoryID, booking.CheckInDate, booking.CheckOutDate); err != nil {
+			return err
+		}
+	}
+	if booking.RoomID == nil {
+		return nil
+	}
+	return releaseRoomNights(tx, *booking.RoomID, booking.CheckInDate, booking.CheckOutDate)
+}
+
+// bookedInventory describes what a booking holds, for status history reasons
+func bookedInventory(booking *models.HotelBooking) string {
+	if booking.RoomID != nil {
+		return fmt.Sprintf("room %d", *booking.RoomID)
+	}
+	return fmt.Sprintf("room category %d", *booking.RoomCategoryID)
+}
+
+func holdsInventory(status string) bool {
+	for _, holding := range inventoryHoldingStatuses {
+		if status == holding {
+			return true
+		}
+	}
+	return false
 }
 
 func (s *BookingService) GetBookingsByHotel(hotelID uint) ([]models.HotelBooking, error) {
@@ -90,8 +1293,15 @@ func (s *BookingService) GetBookingsByStatus(status string) ([]models.HotelBooki
 	return bookings, err
 }
 
-func (s *BookingService) GetBookingsByGuestEmail(email string) ([]models.HotelBooking, error) {
-	var bookings []models.HotelBooking
-	err := s.db.Preload("Hotel").Preload("Room").Where("guest_email = ?", email).Find(&bookings).Error
-	return bookings, err
+// LookupBooking finds a booking for guest self-service. Both the reference and the guest email
+// must match, so knowing one of them is not enough to see a booking.
+func (s *BookingService) LookupBooking(reference, email string) (*models.HotelBooking, error) {
+	var booking models.HotelBooking
+	err := s.db.Preload("Hotel").Preload("Room").
+		Where("booking_reference = ? AND LOWER(guest_email) = LOWER(?)", strings.TrimSpace(reference), strings.TrimSpace(email)).
+		First(&booking).Error
+	if err != nil {
+		return nil, err
+	}
+	return &booking, nil
 }
\ No newline at end of file
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"flyola-services/internal/models"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CancellationQuote is the refund a booking would get if cancelled at QuotedAt
+type CancellationQuote struct {
+	Rules         []models.CancellationRule `json:"rules"`
+	StartsAt      time.Time                 `json:"starts_at"`
+	QuotedAt      time.Time                 `json:"quoted_at"`
+	HoursBefore   float64                   `json:"hours_before"`
+	RefundPercent float64                   `json:"refund_percent"`
+	PaidAmount    float64                   `json:"paid_amount"`
+	RefundAmount  float64                   `json:"refund_amount"`
+}
+
+type CancellationPolicyService struct {
+	db *gorm.DB
+}
+
+func NewCancellationPolicyService(db *gorm.DB) *CancellationPolicyService {
+	return &CancellationPolicyService{db: db}
+}
+
+// GetHotelPolicy returns the hotel's cancellation policy, or an unsaved policy with the default
+// rules if the hotel has none
+func (s *CancellationPolicyService) GetHotelPolicy(hotelID uint) (*models.CancellationPolicy, error) {
+	if err := s.db.Select("id").First(&models.Hotel{}, hotelID).Error; err != nil {
+		return nil, err
+	}
+	return findCancellationPolicy(s.db, "hotel_id", hotelID)
+}
+
+// SetHotelPolicy replaces the hotel's cancellation rules. Existing bookings keep the rules they were made under.
+func (s *CancellationPolicyService) SetHotelPolicy(hotelID uint, rules []models.CancellationRule) (*models.CancellationPolicy, error) {
+	if err := s.db.Select("id").First(&models.Hotel{}, hotelID).Error; err != nil {
+		return nil, err
+	}
+	return s.savePolicy(&models.CancellationPolicy{HotelID: &hotelID}, "hotel_id", hotelID, rules)
+}
+
+// GetPackagePolicy returns the package's cancellation policy, or an unsaved policy with the default
+// rules if the package has none
+func (s *CancellationPolicyService) GetPackagePolicy(packageID uint) (*models.CancellationPolicy, error) {
+	if err := s.db.Select("id").First(&models.HolidayPackage{}, packageID).Error; err != nil {
+		return nil, err
+	}
+	return findCancellationPolicy(s.db, "package_id", packageID)
+}
+
+// SetPackagePolicy replaces the package's cancellation rules. Existing bookings keep the rules they were made under.
+func (s *CancellationPolicyService) SetPackagePolicy(packageID uint, rules []models.CancellationRule) (*models.CancellationPolicy, error) {
+	if err := s.db.Select("id").First(&models.HolidayPackage{}, packageID).Error; err != nil {
+		return nil, err
+	}
+	return s.savePolicy(&models.CancellationPolicy{PackageID: &packageID}, "package_id", packageID, rules)
+}
+
+func (s *CancellationPolicyService) savePolicy(policy *models.CancellationPolicy, column string, ownerID uint, rules []models.CancellationRule) (*models.CancellationPolicy, error) {
+	if err := validateCancellationRules(rules); err != nil {
+		return nil, err
+	}
+	if err := policy.SetRules(sortCancellationRules(rules)); err != nil {
+		return nil, err
+	}
+
+	err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: column}},
+		DoUpdates: clause.AssignmentColumns([]string{"rules", "updated_at"}),
+	}).Create(policy).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return findCancellationPolicy(s.db, column, ownerID)
+}
+
+func findCancellationPolicy(db *gorm.DB, column string, ownerID uint) (*models.CancellationPolicy, error) {
+	var policy models.CancellationPolicy
+	err := db.Where(column+" = ?", ownerID).First(&policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		policy = models.CancellationPolicy{}
+		if column == "hotel_id" {
+			policy.HotelID = &ownerID
+		} else {
+			policy.PackageID = &ownerID
+		}
+		if err := policy.SetRules(models.DefaultCancellationRules); err != nil {
+			return nil, err
+		}
+		return &policy, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// cancellationPolicySnapshot returns the rules currently in force for a hotel or package, to be
+// stored on a new booking
+func cancellationPolicySnapshot(tx *gorm.DB, column string, ownerID uint) (datatypes.JSON, error) {
+	policy, err := findCancellationPolicy(tx, column, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	return policy.Rules, nil
+}
+
+func validateCancellationRules(rules []models.CancellationRule) error {
+	if len(rules) == 0 {
+		return &BookingValidationError{Message: "A cancellation policy needs at least one rule"}
+	}
+
+	seen := make(map[int]bool, len(rules))
+	for _, rule := range rules {
+		if rule.HoursBefore < 0 {
+			return &BookingValidationError{Message: "hours_before cannot be negative"}
+		}
+		if rule.RefundPercent < 0 || rule.RefundPercent > 100 {
+			return &BookingValidationError{Message: "refund_percent must be between 0 and 100"}
+		}
+		if seen[rule.HoursBefore] {
+			return &BookingValidationError{Message: fmt.Sprintf("More than one rule for %d hours before", rule.HoursBefore)}
+		}
+		seen[rule.HoursBefore] = true
+	}
+	return nil
+}
+
+// sortCancellationRules orders rules from the earliest cancellation window to the latest
+func sortCancellationRules(rules []models.CancellationRule) []models.CancellationRule {
+	sorted := append([]models.CancellationRule(nil), rules...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].HoursBefore > sorted[j].HoursBefore
+	})
+	return sorted
+}
+
+// quoteCancellation works out the refund for cancelling at now. The first rule whose window has
+// not yet closed applies; once check-in or travel has started nothing is refunded.
+func quoteCancellation(snapshot datatypes.JSON, startsAt time.Time, paidAmount float64, now time.Time) *CancellationQuote {
+	var rules []models.CancellationRule
+	if len(snapshot) == 0 || json.Unmarshal(snapshot, &rules) != nil || len(rules) == 0 {
+		// Bookings made before policies existed fall back to the defaults
+		rules = models.DefaultCancellationRules
+	}
+	rules = sortCancellationRules(rules)
+
+	quote := &CancellationQuote{
+		Rules:       rules,
+		StartsAt:    startsAt,
+		QuotedAt:    now,
+		HoursBefore: math.Floor(startsAt.Sub(now).Hours()*100) / 100,
+		PaidAmount:  paidAmount,
+	}
+	if now.Before(startsAt) {
+		for _, rule := range rules {
+			if quote.HoursBefore >= float64(rule.HoursBefore) {
+				quote.RefundPercent = rule.RefundPercent
+				break
+			}
+		}
+	}
+	quote.RefundAmount = roundAmount(paidAmount * quote.RefundPercent / 100)
+	return quote
+}
+
+// checkInTime returns when a stay starts: the check-in date at the hotel's check-in time, or
+// midnight if the hotel has not set one
+func checkInTime(checkInDate time.Time, hotelCheckInTime string) time.Time {
+	day := dateOnly(checkInDate)
+	if at, err := time.Parse("15:04", hotelCheckInTime); err == nil {
+		return day.Add(time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute)
+	}
+	return day
+}
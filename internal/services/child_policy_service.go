@@ -0,0 +1,192 @@
+package services
+
+import (
+	"errors"
+	"flyola-services/internal/models"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxChildAge is the oldest age a child age band may cover
+const maxChildAge = 17
+
+// ChildCharge is the line of a price breakdown for a child priced by the hotel's child policy
+type ChildCharge struct {
+	Age          int     `json:"age"`
+	Band         string  `json:"band"`
+	NightlyPrice float64 `json:"nightly_price"`
+	Total        float64 `json:"total"`
+}
+
+// stayParty is the guests of a room split by the hotel's child policy. Occupancy is the number of
+// guests counted against the room category's maximum occupancy.
+type stayParty struct {
+	Adults    int
+	Occupancy int
+	Children  []ChildCharge
+}
+
+type ChildPolicyService struct {
+	db *gorm.DB
+}
+
+func NewChildPolicyService(db *gorm.DB) *ChildPolicyService {
+	return &ChildPolicyService{db: db}
+}
+
+// GetHotelPolicy returns the hotel's child policy, or an unsaved policy without bands if the hotel
+// has none
+func (s *ChildPolicyService) GetHotelPolicy(hotelID uint) (*models.ChildPolicy, error) {
+	if err := s.db.Select("id").First(&models.Hotel{}, hotelID).Error; err != nil {
+		return nil, err
+	}
+	policy, err := findChildPolicy(s.db, hotelID)
+	if err != nil || policy != nil {
+		return policy, err
+	}
+
+	policy = &models.ChildPolicy{HotelID: hotelID}
+	if err := policy.SetBands([]models.ChildAgeBand{}); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// SetHotelPolicy replaces the hotel's child age bands. Existing bookings keep their price until modified.
+func (s *ChildPolicyService) SetHotelPolicy(hotelID uint, bands []models.ChildAgeBand) (*models.ChildPolicy, error) {
+	if err := s.db.Select("id").First(&models.Hotel{}, hotelID).Error; err != nil {
+		return nil, err
+	}
+	sorted, err := validateChildAgeBands(bands)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &models.ChildPolicy{HotelID: hotelID}
+	if err := policy.SetBands(sorted); err != nil {
+		return nil, err
+	}
+	err = s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "hotel_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"bands", "updated_at"}),
+	}).Create(policy).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return findChildPolicy(s.db, hotelID)
+}
+
+// DeleteHotelPolicy removes the hotel's child policy, so every guest is priced as an adult
+func (s *ChildPolicyService) DeleteHotelPolicy(hotelID uint) error {
+	return s.db.Where("hotel_id = ?", hotelID).Delete(&models.ChildPolicy{}).Error
+}
+
+// validateChildAgeBands checks that bands cover distinct ages of children and returns them
+// ordered by age
+func validateChildAgeBands(bands []models.ChildAgeBand) ([]models.ChildAgeBand, error) {
+	sorted := make([]models.ChildAgeBand, len(bands))
+	copy(sorted, bands)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].MinAge < sorted[j].MinAge
+	})
+
+	for i := range sorted {
+		band := &sorted[i]
+		band.Name = strings.TrimSpace(band.Name)
+		if band.Name == "" {
+			return nil, &BookingValidationError{Message: "Every age band needs a name"}
+		}
+		if band.MinAge < 0 || band.MaxAge < band.MinAge || band.MaxAge > maxChildAge {
+			return nil, &BookingValidationError{
+				Message: fmt.Sprintf("Age band %s must cover ages from 0 to %d with minAge not above maxAge", band.Name, maxChildAge),
+			}
+		}
+		if band.NightlyPrice < 0 {
+			return nil, &BookingValidationError{Message: fmt.Sprintf("Age band %s cannot have a negative nightlyPrice", band.Name)}
+		}
+		if i > 0 && band.MinAge <= sorted[i-1].MaxAge {
+			return nil, &BookingValidationError{
+				Message: fmt.Sprintf("Age bands %s and %s overlap", sorted[i-1].Name, band.Name),
+			}
+		}
+	}
+	return sorted, nil
+}
+
+// findChildPolicy returns a hotel's child policy, or nil if it has none
+func findChildPolicy(tx *gorm.DB, hotelID uint) (*models.ChildPolicy, error) {
+	var policy models.ChildPolicy
+	err := tx.Where("hotel_id = ?", hotelID).First(&policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// loadChildPolicies loads the child policies of several hotels, keyed by hotel
+func loadChildPolicies(tx *gorm.DB, hotelIDs []uint) (map[uint]*models.ChildPolicy, error) {
+	var policies []models.ChildPolicy
+	if err := tx.Where("hotel_id IN ?", hotelIDs).Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	byHotel := make(map[uint]*models.ChildPolicy, len(policies))
+	for i := range policies {
+		byHotel[policies[i].HotelID] = &policies[i]
+	}
+	return byHotel, nil
+}
+
+// splitParty splits a number of guests into adults and children by the ages given for some of
+// them. Guests without an age, and ages outside every band of the policy, are adults; without a
+// policy every guest is an adult.
+func splitParty(policy *models.ChildPolicy, guests int, ages []int) (*stayParty, error) {
+	if len(ages) > guests {
+		return nil, &BookingValidationError{
+			Message: fmt.Sprintf("%d child age(s) given for a booking of %d guest(s)", len(ages), guests),
+		}
+	}
+
+	var bands []models.ChildAgeBand
+	if policy != nil {
+		bands = policy.GetBands()
+	}
+
+	party := &stayParty{Adults: guests, Occupancy: guests}
+	for _, age := range ages {
+		if age < 0 {
+			return nil, &BookingValidationError{Message: "Child ages cannot be negative"}
+		}
+		for _, band := range bands {
+			if age < band.MinAge || age > band.MaxAge {
+				continue
+			}
+			party.Adults--
+			if !band.CountsTowardOccupancy {
+				party.Occupancy--
+			}
+			party.Children = append(party.Children, ChildCharge{Age: age, Band: band.Name, NightlyPrice: band.NightlyPrice})
+			break
+		}
+	}
+	if party.Adults < 1 {
+		return nil, &BookingValidationError{Message: "At least one adult must stay in the room"}
+	}
+	return party, nil
+}
+
+// childAges returns the ages of the guests priced as children
+func (p *stayParty) childAges() []int {
+	ages := make([]int, len(p.Children))
+	for i, child := range p.Children {
+		ages[i] = child.Age
+	}
+	return ages
+}
@@ -0,0 +1,453 @@
+package services
+
+import (
+	"errors"
+	"flyola-services/internal/models"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxDynamicPricingDays caps the lookahead of a dynamic pricing rule and the range of a preview
+const maxDynamicPricingDays = 366
+
+// DynamicPriceSuggestion is the price dynamic pricing sets for a room category on one night
+type DynamicPriceSuggestion struct {
+	Date           string                `json:"date"`
+	RuleID         uint                  `json:"ruleId"`
+	RoomCategoryID uint                  `json:"roomCategoryId"`
+	TotalRooms     int                   `json:"totalRooms"`
+	BookedRooms    int                   `json:"bookedRooms"`
+	Occupancy      float64               `json:"occupancy"` // Percent of the category's rooms booked
+	AdjustPercent  float64               `json:"adjustPercent"`
+	Rooms          []RoomPriceSuggestion `json:"rooms"`
+}
+
+// RoomPriceSuggestion is the suggested price of one free room for the night. Rooms with a manual
+// price keep it and are only shown for reference.
+type RoomPriceSuggestion struct {
+	RoomID         uint    `json:"roomId"`
+	CurrentPrice   float64 `json:"currentPrice"`
+	CurrentSource  string  `json:"currentSource"`
+	SuggestedPrice float64 `json:"suggestedPrice"`
+	Manual         bool    `json:"manual"`
+}
+
+type DynamicPricingService struct {
+	db *gorm.DB
+}
+
+func NewDynamicPricingService(db *gorm.DB) *DynamicPricingService {
+	return &DynamicPricingService{db: db}
+}
+
+func (s *DynamicPricingService) GetHotelRules(hotelID uint) ([]models.DynamicPricingRule, error) {
+	var rules []models.DynamicPricingRule
+	err := s.db.Where("hotel_id = ?", hotelID).Order("id").Find(&rules).Error
+	return rules, err
+}
+
+// SetRule creates or replaces the hotel's rule for rule.RoomCategoryID (or its hotel-wide rule).
+// Disabling a rule hands the nights it priced back to the calendar's base prices.
+func (s *DynamicPricingService) SetRule(hotelID uint, rule *models.DynamicPricingRule) (*models.DynamicPricingRule, error) {
+	rule.HotelID = hotelID
+	if err := s.validateRule(rule); err != nil {
+		return nil, err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var existing models.DynamicPricingRule
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("hotel_id = ?", hotelID)
+		if rule.RoomCategoryID != nil {
+			query = query.Where("room_category_id = ?", *rule.RoomCategoryID)
+		} else {
+			query = query.Where("room_category_id IS NULL")
+		}
+		err := query.First(&existing).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if err == nil {
+			rule.ID = existing.ID
+			rule.CreatedAt = existing.CreatedAt
+			if err := tx.Model(&existing).
+				Select("enabled", "bands", "floor_price", "ceiling_price", "lookahead_days").
+				Updates(rule).Error; err != nil {
+				return err
+			}
+			if existing.Enabled && !rule.Enabled {
+				return resetDynamicPrices(tx, hotelID, rule.RoomCategoryID)
+			}
+			return nil
+		}
+		return tx.Create(rule).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// DeleteRule removes a rule and hands the nights it priced back to the calendar's base prices
+func (s *DynamicPricingService) DeleteRule(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var rule models.DynamicPricingRule
+		if err := tx.First(&rule, id).Error; err != nil {
+			return err
+		}
+		if err := resetDynamicPrices(tx, rule.HotelID, rule.RoomCategoryID); err != nil {
+			return err
+		}
+		return tx.Delete(&rule).Error
+	})
+}
+
+// PreviewHotel is a dry run: it returns the prices the hotel's rules would set from one date up to
+// another without changing anything. Disabled rules are included so they can be tried out first.
+func (s *DynamicPricingService) PreviewHotel(hotelID uint, from, to time.Time) ([]DynamicPriceSuggestion, error) {
+	from, to = dateOnly(from), dateOnly(to)
+	if !to.After(from) {
+		return nil, &BookingValidationError{Message: "to must be after from"}
+	}
+	if len(stayNights(from, to)) > maxDynamicPricingDays {
+		return nil, &BookingValidationError{Message: fmt.Sprintf("Prices can be previewed for at most %d nights", maxDynamicPricingDays)}
+	}
+
+	if err := s.db.Select("id").First(&models.Hotel{}, hotelID).Error; err != nil {
+		return nil, err
+	}
+	rules, err := s.GetHotelRules(hotelID)
+	if err != nil {
+		return nil, err
+	}
+	return suggestDynamicPrices(s.db, hotelID, rules, from, to)
+}
+
+// ApplyHotel sets the prices suggested by a hotel's enabled rules on every free room night within
+// their lookahead and records each change. The rules are claimed with SKIP LOCKED, so a hotel being
+// repriced by another server replica is skipped. Returns the number of room nights repriced.
+func (s *DynamicPricingService) ApplyHotel(hotelID uint) (int, error) {
+	changed := 0
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var rules []models.DynamicPricingRule
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("hotel_id = ? AND enabled = ?", hotelID, true).
+			Order("id").Find(&rules).Error; err != nil {
+			return err
+		}
+		if len(rules) == 0 {
+			return nil
+		}
+
+		lookahead := 0
+		for _, rule := range rules {
+			if rule.LookaheadDays > lookahead {
+				lookahead = rule.LookaheadDays
+			}
+		}
+		from := dateOnly(time.Now())
+		suggestions, err := suggestDynamicPrices(tx, hotelID, rules, from, from.AddDate(0, 0, lookahead))
+		if err != nil {
+			return err
+		}
+
+		lookaheads := make(map[uint]time.Time, len(rules))
+		for _, rule := range rules {
+			lookaheads[rule.ID] = from.AddDate(0, 0, rule.LookaheadDays)
+		}
+
+		var changes []models.DynamicPriceChange
+		for _, suggestion := range suggestions {
+			date, _ := time.ParseInLocation("2006-01-02", suggestion.Date, time.Local)
+			if !date.Before(lookaheads[suggestion.RuleID]) {
+				continue
+			}
+			for _, room := range suggestion.Rooms {
+				if room.Manual || (room.CurrentSource == models.PriceSourceDynamic && room.CurrentPrice == room.SuggestedPrice) {
+					continue
+				}
+				if err := setDynamicPrice(tx, room.RoomID, date, room.SuggestedPrice); err != nil {
+					return err
+				}
+				changes = append(changes, models.DynamicPriceChange{
+					RuleID:         suggestion.RuleID,
+					HotelID:        hotelID,
+					RoomCategoryID: suggestion.RoomCategoryID,
+					RoomID:         room.RoomID,
+					Date:           date,
+					Occupancy:      suggestion.Occupancy,
+					AdjustPercent:  suggestion.AdjustPercent,
+					OldPrice:       room.CurrentPrice,
+					NewPrice:       room.SuggestedPrice,
+				})
+			}
+		}
+
+		if len(changes) > 0 {
+			if err := tx.CreateInBatches(&changes, bulkWriteBatch).Error; err != nil {
+				return err
+			}
+		}
+		changed = len(changes)
+		return nil
+	})
+	return changed, err
+}
+
+// ApplyAll reprices every hotel with an enabled rule. Returns the number of room nights repriced.
+func (s *DynamicPricingService) ApplyAll() (int, error) {
+	var hotelIDs []uint
+	if err := s.db.Model(&models.DynamicPricingRule{}).Where("enabled = ?", true).
+		Distinct().Pluck("hotel_id", &hotelIDs).Error; err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, hotelID := range hotelIDs {
+		changed, err := s.ApplyHotel(hotelID)
+		if err != nil {
+			return total, err
+		}
+		total += changed
+	}
+	return total, nil
+}
+
+// GetPriceChanges lists the price changes dynamic pricing made to a hotel's nights from one date
+// to another (both inclusive), newest first
+func (s *DynamicPricingService) GetPriceChanges(hotelID uint, from, to time.Time) ([]models.DynamicPriceChange, error) {
+	var changes []models.DynamicPriceChange
+	err := s.db.Where("hotel_id = ? AND date >= ? AND date <= ?", hotelID, dateOnly(from), dateOnly(to)).
+		Order("created_at DESC, id DESC").Find(&changes).Error
+	return changes, err
+}
+
+func (s *DynamicPricingService) validateRule(rule *models.DynamicPricingRule) error {
+	bands := rule.GetBands()
+	if len(bands) == 0 {
+		return &BookingValidationError{Message: "at least one occupancy band is required"}
+	}
+	seen := make(map[float64]bool, len(bands))
+	for _, band := range bands {
+		if band.MinOccupancy < 0 || band.MinOccupancy > 100 {
+			return &BookingValidationError{Message: "minOccupancy must be between 0 and 100"}
+		}
+		if band.AdjustPercent <= -100 {
+			return &BookingValidationError{Message: "adjustPercent must be greater than -100"}
+		}
+		if seen[band.MinOccupancy] {
+			return &BookingValidationError{Message: fmt.Sprintf("two bands start at %.0f%% occupancy", band.MinOccupancy)}
+		}
+		seen[band.MinOccupancy] = true
+	}
+
+	if rule.FloorPrice < 0 || rule.CeilingPrice < 0 {
+		return &BookingValidationError{Message: "floorPrice and ceilingPrice cannot be negative"}
+	}
+	if rule.FloorPrice > 0 && rule.CeilingPrice > 0 && rule.CeilingPrice < rule.FloorPrice {
+		return &BookingValidationError{Message: "ceilingPrice must not be below floorPrice"}
+	}
+	if rule.LookaheadDays == 0 {
+		rule.LookaheadDays = 90
+	}
+	if rule.LookaheadDays < 0 || rule.LookaheadDays > maxDynamicPricingDays {
+		return &BookingValidationError{Message: fmt.Sprintf("lookaheadDays must be between 1 and %d", maxDynamicPricingDays)}
+	}
+
+	if err := s.db.Select("id").First(&models.Hotel{}, rule.HotelID).Error; err != nil {
+		return err
+	}
+	if rule.RoomCategoryID != nil {
+		var category models.RoomCategory
+		if err := s.db.Select("id", "hotel_id").First(&category, *rule.RoomCategoryID).Error; err != nil {
+			return err
+		}
+		if category.HotelID != nil && *category.HotelID != rule.HotelID {
+			return &BookingValidationError{Message: "roomCategoryId belongs to another hotel"}
+		}
+	}
+	return nil
+}
+
+// suggestDynamicPrices works out the price each rule sets on the free rooms of its category for
+// every night from one date up to another, in a handful of queries. A room's reference price is
+// its base price adjusted by the hotel's rate plans.
+func suggestDynamicPrices(tx *gorm.DB, hotelID uint, rules []models.DynamicPricingRule, from, to time.Time) ([]DynamicPriceSuggestion, error) {
+	if len(rules) == 0 {
+		return []DynamicPriceSuggestion{}, nil
+	}
+
+	var rooms []models.Room
+	if err := tx.Select("id", "hotel_id", "room_category_id", "base_price").
+		Where("hotel_id = ? AND status = ?", hotelID, 0).
+		Order("id").Find(&rooms).Error; err != nil {
+		return nil, err
+	}
+	if len(rooms) == 0 {
+		return []DynamicPriceSuggestion{}, nil
+	}
+
+	roomIDs := make([]uint, len(rooms))
+	byCategory := make(map[uint][]*models.Room)
+	for i := range rooms {
+		roomIDs[i] = rooms[i].ID
+		byCategory[rooms[i].RoomCategoryID] = append(byCategory[rooms[i].RoomCategoryID], &rooms[i])
+	}
+
+	var bookings []models.HotelBooking
+	if err := tx.Select("room_id", "check_in_date", "check_out_date").
+		Where("room_id IN ? AND booking_status IN ?", roomIDs, inventoryHoldingStatuses).
+		Where("check_in_date < ? AND check_out_date > ?", to, from).
+		Find(&bookings).Error; err != nil {
+		return nil, err
+	}
+	booked := make(map[uint]map[string]bool)
+	for _, booking := range bookings {
+		roomID := *booking.RoomID
+		if booked[roomID] == nil {
+			booked[roomID] = make(map[string]bool)
+		}
+		for _, night := range stayNights(dateOnly(booking.CheckInDate), dateOnly(booking.CheckOutDate)) {
+			booked[roomID][night.Format("2006-01-02")] = true
+		}
+	}
+
+	var availabilities []models.RoomAvailability
+	if err := tx.Select("room_id", "date", "price", "price_source").
+		Where("room_id IN ? AND date >= ? AND date < ?", roomIDs, from, to).
+		Find(&availabilities).Error; err != nil {
+		return nil, err
+	}
+	current := make(map[uint]map[string]models.RoomAvailability)
+	for _, availability := range availabilities {
+		if current[availability.RoomID] == nil {
+			current[availability.RoomID] = make(map[string]models.RoomAvailability)
+		}
+		current[availability.RoomID][availability.Date.Format("2006-01-02")] = availability
+	}
+
+	ratePlans, err := loadRatePlanRules(tx, []uint{hotelID}, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// A category's own rule beats the hotel-wide one
+	var hotelWide *models.DynamicPricingRule
+	categoryRules := make(map[uint]*models.DynamicPricingRule)
+	for i := range rules {
+		if rules[i].RoomCategoryID == nil {
+			hotelWide = &rules[i]
+		} else {
+			categoryRules[*rules[i].RoomCategoryID] = &rules[i]
+		}
+	}
+
+	categoryIDs := make([]uint, 0, len(byCategory))
+	for categoryID := range byCategory {
+		categoryIDs = append(categoryIDs, categoryID)
+	}
+	sort.Slice(categoryIDs, func(i, j int) bool { return categoryIDs[i] < categoryIDs[j] })
+
+	suggestions := []DynamicPriceSuggestion{}
+	for _, night := range stayNights(from, to) {
+		key := night.Format("2006-01-02")
+		for _, categoryID := range categoryIDs {
+			rule := categoryRules[categoryID]
+			if rule == nil {
+				rule = hotelWide
+			}
+			if rule == nil {
+				continue
+			}
+
+			categoryRooms := byCategory[categoryID]
+			suggestion := DynamicPriceSuggestion{
+				Date:           key,
+				RuleID:         rule.ID,
+				RoomCategoryID: categoryID,
+				TotalRooms:     len(categoryRooms),
+				Rooms:          []RoomPriceSuggestion{},
+			}
+			for _, room := range categoryRooms {
+				if booked[room.ID][key] {
+					suggestion.BookedRooms++
+				}
+			}
+			suggestion.Occupancy = roundAmount(float64(suggestion.BookedRooms) * 100 / float64(suggestion.TotalRooms))
+			suggestion.AdjustPercent = occupancyAdjustment(rule.GetBands(), suggestion.Occupancy)
+
+			for _, room := range categoryRooms {
+				if booked[room.ID][key] {
+					continue
+				}
+				reference := nightlyRoomRate(room, night, room.BasePrice, nil, ratePlans).Price
+				price := reference + reference*suggestion.AdjustPercent/100
+				if rule.FloorPrice > 0 && price < rule.FloorPrice {
+					price = rule.FloorPrice
+				}
+				if rule.CeilingPrice > 0 && price > rule.CeilingPrice {
+					price = rule.CeilingPrice
+				}
+
+				existing, ok := current[room.ID][key]
+				roomSuggestion := RoomPriceSuggestion{
+					RoomID:         room.ID,
+					CurrentPrice:   room.BasePrice,
+					CurrentSource:  models.PriceSourceBase,
+					SuggestedPrice: roundAmount(price),
+				}
+				if ok {
+					roomSuggestion.CurrentPrice = existing.Price
+					roomSuggestion.CurrentSource = existing.PriceSource
+					roomSuggestion.Manual = existing.PriceSource == models.PriceSourceManual && existing.Price > 0
+				}
+				suggestion.Rooms = append(suggestion.Rooms, roomSuggestion)
+			}
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+	return suggestions, nil
+}
+
+// occupancyAdjustment returns the adjustment of the highest band the occupancy reaches, or 0
+func occupancyAdjustment(bands []models.OccupancyBand, occupancy float64) float64 {
+	adjustment, reached := 0.0, -1.0
+	for _, band := range bands {
+		if occupancy >= band.MinOccupancy && band.MinOccupancy > reached {
+			adjustment, reached = band.AdjustPercent, band.MinOccupancy
+		}
+	}
+	return adjustment
+}
+
+// setDynamicPrice sets a dynamic price on a room night, creating its availability row if needed
+func setDynamicPrice(tx *gorm.DB, roomID uint, date time.Time, price float64) error {
+	availability := models.RoomAvailability{
+		RoomID:      roomID,
+		Date:        date,
+		IsAvailable: true,
+		Price:       price,
+		PriceSource: models.PriceSourceDynamic,
+	}
+	return tx.Omit("Room").Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "room_id"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"price", "price_source", "updated_at"}),
+	}).Create(&availability).Error
+}
+
+// resetDynamicPrices hands the future nights dynamic pricing set for a hotel (or one of its room
+// categories) back to the rooms' base prices, which the calendar generator keeps up to date
+func resetDynamicPrices(tx *gorm.DB, hotelID uint, roomCategoryID *uint) error {
+	rooms := tx.Model(&models.Room{}).Select("id").Where("hotel_id = ?", hotelID)
+	if roomCategoryID != nil {
+		rooms = rooms.Where("room_category_id = ?", *roomCategoryID)
+	}
+	return tx.Exec(`UPDATE room_availability a JOIN rooms r ON r.id = a.room_id
+		SET a.price = r.base_price, a.price_source = ?, a.updated_at = ?
+		WHERE a.room_id IN (?) AND a.price_source = ? AND a.date >= ?`,
+		models.PriceSourceBase, time.Now(), rooms, models.PriceSourceDynamic, dateOnly(time.Now())).Error
+}
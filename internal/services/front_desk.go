@@ -0,0 +1,330 @@
+package services
+
+import (
+	"flyola-services/internal/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CheckInRequest records a guest's arrival. RoomID assigns the room of a category booking, or
+// confirms the room of a room booking. Guests, when given, replace the booking's guests; the main
+// guest must end up with ID details. ArrivedAt defaults to now.
+type CheckInRequest struct {
+	RoomID    *uint               `json:"room_id"`
+	Guests    []models.HotelGuest `json:"guests"`
+	ArrivedAt *time.Time          `json:"arrived_at"`
+}
+
+// FolioChargeRequest is an incidental to post to a booking's folio
+type FolioChargeRequest struct {
+	Category    string  `json:"category"`
+	Description string  `json:"description" binding:"required"`
+	Quantity    int     `json:"quantity"`
+	UnitPrice   float64 `json:"unit_price"`
+}
+
+// CheckOutRequest records a guest's departure, posting any last incidentals before the folio is
+// closed. DepartedAt defaults to now.
+type CheckOutRequest struct {
+	Charges    []FolioChargeRequest `json:"charges"`
+	DepartedAt *time.Time           `json:"departed_at"`
+}
+
+// Folio is the bill of a stay: the room amount, the incidentals charged to it and what is left to
+// pay. The room amount is due as well while the booking is unpaid.
+type Folio struct {
+	BookingID         uint                 `json:"booking_id"`
+	RoomAmount        float64              `json:"room_amount"`
+	RoomPaid          bool                 `json:"room_paid"`
+	Charges           []models.FolioCharge `json:"charges"`
+	IncidentalsAmount float64              `json:"incidentals_amount"`
+	BalanceDue        float64              `json:"balance_due"`
+	Closed            bool                 `json:"closed"`
+	ClosedAt          *time.Time           `json:"closed_at,omitempty"`
+}
+
+// FrontDeskList is a hotel's day at the front desk: guests due to arrive, guests due to leave and
+// guests staying the night
+type FrontDeskList struct {
+	Date       string                `json:"date"`
+	Arrivals   []models.HotelBooking `json:"arrivals"`
+	Departures []models.HotelBooking `json:"departures"`
+	InHouse    []models.HotelBooking `json:"in_house"`
+}
+
+// CheckIn checks a confirmed booking in on or after its check-in date. The booking must have a room
+// and a main guest with ID details by then.
+func (s *BookingService) CheckIn(id uint, request *CheckInRequest, actor string) (*models.HotelBooking, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var booking models.HotelBooking
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, id).Error; err != nil {
+			return err
+		}
+		if !models.CanTransitionBookingStatus(booking.BookingStatus, models.BookingStatusCheckedIn) {
+			return &InvalidTransitionError{From: booking.BookingStatus, To: models.BookingStatusCheckedIn}
+		}
+
+		arrivedAt := time.Now()
+		if request.ArrivedAt != nil {
+			arrivedAt = *request.ArrivedAt
+		}
+		arrival := dateOnly(arrivedAt)
+		if arrival.Before(dateOnly(booking.CheckInDate)) {
+			return &BookingValidationError{
+				Message: "The guest cannot check in before " + booking.CheckInDate.Format("2006-01-02") + "; modify the booking to arrive earlier",
+			}
+		}
+		if !arrival.Before(dateOnly(booking.CheckOutDate)) {
+			return &BookingValidationError{Message: "The stay has already ended"}
+		}
+
+		if request.RoomID != nil && (booking.RoomID == nil || *booking.RoomID != *request.RoomID) {
+			if booking.InventoryType != models.InventoryTypeAllotment {
+				return &BookingValidationError{Message: "room_id does not match the booked room; use modify to move a room booking"}
+			}
+			if err := assignRoom(tx, &booking, *request.RoomID); err != nil {
+				return err
+			}
+		}
+
+		if err := captureCheckInGuests(tx, &booking, request.Guests); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.HotelBooking{}).Where("id = ?", booking.ID).
+			Update("checked_in_at", arrivedAt).Error; err != nil {
+			return err
+		}
+		reason := "Checked in"
+		if booking.RoomID != nil {
+			reason = fmt.Sprintf("Checked in to room %d", *booking.RoomID)
+		}
+		return transitionBooking(tx, &booking, models.BookingStatusCheckedIn, actor, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetBookingByID(id)
+}
+
+// captureCheckInGuests replaces a booking's guests with those given at check-in, if any, and makes
+// sure the main guest has ID details
+func captureCheckInGuests(tx *gorm.DB, booking *models.HotelBooking, guests []models.HotelGuest) error {
+	if len(guests) > 0 {
+		if err := validateBookingGuests(guests, booking.NumberOfGuests); err != nil {
+			return err
+		}
+		if err := tx.Where("booking_id = ?", booking.ID).Delete(&models.HotelGuest{}).Error; err != nil {
+			return err
+		}
+		for i := range guests {
+			guests[i].ID = 0
+			guests[i].BookingID = booking.ID
+			guests[i].Booking = nil
+		}
+		if err := tx.Create(&guests).Error; err != nil {
+			return err
+		}
+	} else if err := tx.Where("booking_id = ?", booking.ID).Find(&guests).Error; err != nil {
+		return err
+	}
+
+	for _, guest := range guests {
+		if guest.IsMainGuest && strings.TrimSpace(guest.IDType) != "" && strings.TrimSpace(guest.IDNumber) != "" {
+			return nil
+		}
+	}
+	return &BookingValidationError{Message: "The main guest's id_type and id_number are required at check-in"}
+}
+
+// PostFolioCharge charges an incidental to the folio of a checked-in booking
+func (s *BookingService) PostFolioCharge(id uint, request *FolioChargeRequest, actor string) (*models.FolioCharge, error) {
+	var charge *models.FolioCharge
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var booking models.HotelBooking
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, id).Error; err != nil {
+			return err
+		}
+		if err := checkFolioOpen(&booking); err != nil {
+			return err
+		}
+
+		var err error
+		charge, err = postFolioCharge(tx, &booking, request, actor)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return charge, nil
+}
+
+// GetFolio returns the folio of a booking
+func (s *BookingService) GetFolio(id uint) (*Folio, error) {
+	var booking models.HotelBooking
+	if err := s.db.First(&booking, id).Error; err != nil {
+		return nil, err
+	}
+	return loadFolio(s.db, &booking)
+}
+
+// CheckOut posts the last incidentals of a checked-in booking, closes its folio and checks it out,
+// which frees the room
+func (s *BookingService) CheckOut(id uint, request *CheckOutRequest, actor string) (*models.HotelBooking, *Folio, error) {
+	var folio *Folio
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var booking models.HotelBooking
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, id).Error; err != nil {
+			return err
+		}
+		if !models.CanTransitionBookingStatus(booking.BookingStatus, models.BookingStatusCheckedOut) {
+			return &InvalidTransitionError{From: booking.BookingStatus, To: models.BookingStatusCheckedOut}
+		}
+
+		departedAt := time.Now()
+		if request.DepartedAt != nil {
+			departedAt = *request.DepartedAt
+		}
+		if booking.CheckedInAt != nil && departedAt.Before(*booking.CheckedInAt) {
+			return &BookingValidationError{Message: "departed_at cannot be before the guest checked in"}
+		}
+
+		for i := range request.Charges {
+			if _, err := postFolioCharge(tx, &booking, &request.Charges[i], actor); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		if folio, err = loadFolio(tx, &booking); err != nil {
+			return err
+		}
+		closedAt := time.Now()
+		folio.Closed = true
+		folio.ClosedAt = &closedAt
+
+		if err := tx.Model(&models.HotelBooking{}).Where("id = ?", booking.ID).Updates(map[string]interface{}{
+			"checked_out_at":     departedAt,
+			"incidentals_amount": folio.IncidentalsAmount,
+			"folio_closed_at":    closedAt,
+		}).Error; err != nil {
+			return err
+		}
+		return transitionBooking(tx, &booking, models.BookingStatusCheckedOut, actor,
+			fmt.Sprintf("Checked out, folio closed with %.2f in incidentals", folio.IncidentalsAmount))
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	booking, err := s.GetBookingByID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return booking, folio, nil
+}
+
+// GetFrontDeskList returns a hotel's arrivals, departures and in-house guests for a date.
+// Unpaid pending bookings are left out of the arrivals, since they cannot be checked in.
+func (s *BookingService) GetFrontDeskList(hotelID uint, date time.Time) (*FrontDeskList, error) {
+	if err := s.db.Select("id").First(&models.Hotel{}, hotelID).Error; err != nil {
+		return nil, err
+	}
+
+	day := dateOnly(date)
+	next := day.AddDate(0, 0, 1)
+	list := &FrontDeskList{Date: day.Format("2006-01-02")}
+
+	query := func() *gorm.DB {
+		return s.db.Preload("Room").Preload("Guests").Where("hotel_id = ?", hotelID).Order("guest_name, id")
+	}
+	if err := query().
+		Where("check_in_date >= ? AND check_in_date < ? AND booking_status IN ?", day, next,
+			[]string{models.BookingStatusConfirmed, models.BookingStatusCheckedIn}).
+		Find(&list.Arrivals).Error; err != nil {
+		return nil, err
+	}
+	if err := query().
+		Where("check_out_date >= ? AND check_out_date < ? AND booking_status IN ?", day, next,
+			[]string{models.BookingStatusCheckedIn, models.BookingStatusCheckedOut}).
+		Find(&list.Departures).Error; err != nil {
+		return nil, err
+	}
+	if err := query().
+		Where("check_in_date < ? AND check_out_date >= ? AND booking_status = ?", next, next, models.BookingStatusCheckedIn).
+		Find(&list.InHouse).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// checkFolioOpen tells whether incidentals can still be charged to a booking
+func checkFolioOpen(booking *models.HotelBooking) error {
+	if booking.BookingStatus != models.BookingStatusCheckedIn || booking.FolioClosedAt != nil {
+		return &BookingValidationError{
+			Message: fmt.Sprintf("Incidentals can only be charged while the guest is checked in, not to a %s booking", booking.BookingStatus),
+		}
+	}
+	return nil
+}
+
+// postFolioCharge adds an incidental to the folio of a locked booking
+func postFolioCharge(tx *gorm.DB, booking *models.HotelBooking, request *FolioChargeRequest, actor string) (*models.FolioCharge, error) {
+	description := strings.TrimSpace(request.Description)
+	if description == "" {
+		return nil, &BookingValidationError{Message: "Every charge needs a description"}
+	}
+	quantity := request.Quantity
+	if quantity == 0 {
+		quantity = 1
+	}
+	if quantity < 0 || request.UnitPrice < 0 {
+		return nil, &BookingValidationError{Message: "quantity and unit_price cannot be negative"}
+	}
+	if actor == "" {
+		actor = ActorSystem
+	}
+
+	charge := &models.FolioCharge{
+		BookingID:   booking.ID,
+		Category:    strings.TrimSpace(request.Category),
+		Description: description,
+		Quantity:    quantity,
+		UnitPrice:   request.UnitPrice,
+		Amount:      roundAmount(float64(quantity) * request.UnitPrice),
+		PostedBy:    actor,
+	}
+	if err := tx.Create(charge).Error; err != nil {
+		return nil, err
+	}
+	return charge, nil
+}
+
+// loadFolio adds up the folio of a booking
+func loadFolio(tx *gorm.DB, booking *models.HotelBooking) (*Folio, error) {
+	folio := &Folio{
+		BookingID:  booking.ID,
+		RoomAmount: booking.FinalAmount,
+		RoomPaid:   booking.PaymentStatus == models.PaymentStatusPaid,
+		Closed:     booking.FolioClosedAt != nil,
+		ClosedAt:   booking.FolioClosedAt,
+	}
+	if err := tx.Where("booking_id = ?", booking.ID).Order("created_at, id").Find(&folio.Charges).Error; err != nil {
+		return nil, err
+	}
+
+	for _, charge := range folio.Charges {
+		folio.IncidentalsAmount += charge.Amount
+	}
+	folio.IncidentalsAmount = roundAmount(folio.IncidentalsAmount)
+	folio.BalanceDue = folio.IncidentalsAmount
+	if !folio.RoomPaid {
+		folio.BalanceDue = roundAmount(folio.BalanceDue + folio.RoomAmount)
+	}
+	return folio, nil
+}
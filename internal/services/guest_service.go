@@ -0,0 +1,192 @@
+package services
+
+import (
+	"errors"
+	"flyola-services/internal/models"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrGuestNotFound is returned when a guest does not exist on the given booking
+var ErrGuestNotFound = errors.New("guest not found")
+
+// GuestUpdate holds the guest details the front desk may change. Nil fields are left unchanged.
+type GuestUpdate struct {
+	Name        *string `json:"name"`
+	Age         *int    `json:"age"`
+	Gender      *string `json:"gender"`
+	IDType      *string `json:"id_type"`
+	IDNumber    *string `json:"id_number"`
+	IsMainGuest *bool   `json:"is_main_guest"`
+}
+
+type GuestService struct {
+	db *gorm.DB
+}
+
+func NewGuestService(db *gorm.DB) *GuestService {
+	return &GuestService{db: db}
+}
+
+func (s *GuestService) GetGuests(bookingID uint) ([]models.HotelGuest, error) {
+	if err := s.db.Select("id").First(&models.HotelBooking{}, bookingID).Error; err != nil {
+		return nil, err
+	}
+
+	var guests []models.HotelGuest
+	err := s.db.Where("booking_id = ?", bookingID).Order("is_main_guest DESC, id ASC").Find(&guests).Error
+	return guests, err
+}
+
+// AddGuest adds a guest to a booking. A booking cannot have more guests than it was booked for,
+// and the first guest added becomes the main guest if there is none yet.
+func (s *GuestService) AddGuest(bookingID uint, guest *models.HotelGuest) error {
+	if strings.TrimSpace(guest.Name) == "" {
+		return &BookingValidationError{Message: "Guest name is required"}
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		booking, err := lockEditableBooking(tx, bookingID)
+		if err != nil {
+			return err
+		}
+
+		var guests []models.HotelGuest
+		if err := tx.Where("booking_id = ?", bookingID).Find(&guests).Error; err != nil {
+			return err
+		}
+		if len(guests) >= booking.NumberOfGuests {
+			return &BookingValidationError{
+				Message: fmt.Sprintf("Booking is for %d guest(s) and all of them are already recorded", booking.NumberOfGuests),
+			}
+		}
+
+		hasMain := false
+		for _, existing := range guests {
+			hasMain = hasMain || existing.IsMainGuest
+		}
+		if guest.IsMainGuest && hasMain {
+			return &BookingValidationError{Message: "Booking already has a main guest"}
+		}
+		if !hasMain {
+			guest.IsMainGuest = true
+		}
+
+		guest.ID = 0
+		guest.BookingID = bookingID
+		guest.Booking = nil
+		return tx.Create(guest).Error
+	})
+}
+
+// UpdateGuest changes a guest's details. Making a guest the main guest demotes the previous one;
+// the main guest cannot be demoted directly, since a booking must always have one.
+func (s *GuestService) UpdateGuest(bookingID, guestID uint, update *GuestUpdate) (*models.HotelGuest, error) {
+	var guest models.HotelGuest
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockEditableBooking(tx, bookingID); err != nil {
+			return err
+		}
+
+		if err := tx.Where("id = ? AND booking_id = ?", guestID, bookingID).First(&guest).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrGuestNotFound
+			}
+			return err
+		}
+
+		updates := map[string]interface{}{}
+		if update.Name != nil {
+			if strings.TrimSpace(*update.Name) == "" {
+				return &BookingValidationError{Message: "Guest name is required"}
+			}
+			updates["name"] = *update.Name
+		}
+		if update.Age != nil {
+			updates["age"] = *update.Age
+		}
+		if update.Gender != nil {
+			updates["gender"] = *update.Gender
+		}
+		if update.IDType != nil {
+			updates["id_type"] = *update.IDType
+		}
+		if update.IDNumber != nil {
+			updates["id_number"] = *update.IDNumber
+		}
+		if update.IsMainGuest != nil && *update.IsMainGuest != guest.IsMainGuest {
+			if !*update.IsMainGuest {
+				return &BookingValidationError{Message: "Make another guest the main guest instead"}
+			}
+			if err := tx.Model(&models.HotelGuest{}).
+				Where("booking_id = ? AND id <> ?", bookingID, guestID).
+				Update("is_main_guest", false).Error; err != nil {
+				return err
+			}
+			updates["is_main_guest"] = true
+		}
+
+		if len(updates) == 0 {
+			return nil
+		}
+		if err := tx.Model(&guest).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.First(&guest, guestID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &guest, nil
+}
+
+// DeleteGuest removes a guest from a booking. The main guest can only be removed once
+// they are the last guest left.
+func (s *GuestService) DeleteGuest(bookingID, guestID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockEditableBooking(tx, bookingID); err != nil {
+			return err
+		}
+
+		var guest models.HotelGuest
+		if err := tx.Where("id = ? AND booking_id = ?", guestID, bookingID).First(&guest).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrGuestNotFound
+			}
+			return err
+		}
+
+		if guest.IsMainGuest {
+			var others int64
+			if err := tx.Model(&models.HotelGuest{}).
+				Where("booking_id = ? AND id <> ?", bookingID, guestID).
+				Count(&others).Error; err != nil {
+				return err
+			}
+			if others > 0 {
+				return &BookingValidationError{Message: "Make another guest the main guest before removing this one"}
+			}
+		}
+
+		return tx.Delete(&guest).Error
+	})
+}
+
+// lockEditableBooking locks a booking whose guest list may still change
+func lockEditableBooking(tx *gorm.DB, bookingID uint) (*models.HotelBooking, error) {
+	var booking models.HotelBooking
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, bookingID).Error; err != nil {
+		return nil, err
+	}
+
+	if !holdsInventory(booking.BookingStatus) {
+		return nil, &BookingValidationError{
+			Message: fmt.Sprintf("Guests cannot be changed on a %s booking", booking.BookingStatus),
+		}
+	}
+	return &booking, nil
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type HolidayPackageService struct {
@@ -112,13 +113,36 @@ func (s *HolidayPackageService) GetPackagesByDate(dateStr string) ([]models.Holi
 	return availablePackages, nil
 }
 
-// CreatePackageBooking creates a new package booking
-func (s *HolidayPackageService) CreatePackageBooking(booking *models.PackageBooking) error {
+// CreatePackageBooking creates a new package booking. Seats held during checkout are taken over
+// with holdToken; the departure must otherwise still have seats for every passenger.
+func (s *HolidayPackageService) CreatePackageBooking(booking *models.PackageBooking, holdToken string) error {
 	return s.db.Transaction(func(tx *gorm.DB) error {
+		var hold *models.InventoryHold
+		if holdToken != "" {
+			var err error
+			if hold, err = takePackageHold(tx, holdToken, booking); err != nil {
+				return err
+			}
+		}
+		if err := checkPackageSeats(tx, booking.PackageID, booking.TravelDate, booking.NumPassengers); err != nil {
+			return err
+		}
+
+		rules, err := cancellationPolicySnapshot(tx, "package_id", booking.PackageID)
+		if err != nil {
+			return err
+		}
+		booking.CancellationRules = rules
+
 		// Create the main booking
 		if err := tx.Create(booking).Error; err != nil {
 			return err
 		}
+		if hold != nil {
+			if err := tx.Model(hold).Update("booking_id", booking.ID).Error; err != nil {
+				return err
+			}
+		}
 
 		// Get package details with schedules
 		var pkg models.HolidayPackage
@@ -359,6 +383,41 @@ func (s *HolidayPackageService) GetBookingByID(id uint) (*models.PackageBooking,
 	return &booking, err
 }
 
+// DeletePackageBooking soft deletes a package booking. Seats are counted from live bookings, so a
+// deleted booking frees its seats without anything to release.
+func (s *HolidayPackageService) DeletePackageBooking(id uint) error {
+	var booking models.PackageBooking
+	if err := s.db.Select("id").First(&booking, id).Error; err != nil {
+		return err
+	}
+	return s.db.Delete(&booking).Error
+}
+
+// RestorePackageBooking brings back a deleted package booking. A pending or confirmed booking needs
+// its seats to still be free on the travel date.
+func (s *HolidayPackageService) RestorePackageBooking(id uint) (*models.PackageBooking, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var booking models.PackageBooking
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, id).Error; err != nil {
+			return err
+		}
+		if !booking.DeletedAt.Valid {
+			return &BookingValidationError{Message: "Package booking is not deleted"}
+		}
+
+		if booking.BookingStatus == "pending" || booking.BookingStatus == "confirmed" {
+			if err := checkPackageSeats(tx, booking.PackageID, booking.TravelDate, booking.NumPassengers); err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Model(&booking).Update("deleted_at", nil).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetBookingByID(id)
+}
+
 // GetBookingByReference retrieves a package booking by booking reference
 func (s *HolidayPackageService) GetBookingByReference(reference string) (*models.PackageBooking, error) {
 	var booking models.PackageBooking
@@ -390,15 +449,22 @@ func (s *HolidayPackageService) UpdatePaymentStatus(id uint, status string, paym
 		Updates(updates).Error
 }
 
-// CancelPackageBooking cancels a package booking and all associated schedule bookings
-func (s *HolidayPackageService) CancelPackageBooking(id uint) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
+// CancelPackageBooking cancels a package booking and all associated schedule bookings. A paid
+// booking is refunded according to the cancellation rules it was made with.
+func (s *HolidayPackageService) CancelPackageBooking(id uint) (*CancellationQuote, error) {
+	var quote *CancellationQuote
+	err := s.db.Transaction(func(tx *gorm.DB) error {
 		// Get booking with schedule bookings
 		var booking models.PackageBooking
-		if err := tx.Preload("PackageScheduleBookings").First(&booking, id).Error; err != nil {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("PackageScheduleBookings").First(&booking, id).Error; err != nil {
 			return err
 		}
 
+		if booking.BookingStatus != "pending" && booking.BookingStatus != "confirmed" {
+			return &InvalidTransitionError{From: booking.BookingStatus, To: "cancelled"}
+		}
+		quote = quotePackageCancellation(&booking, time.Now())
+
 		// Cancel each individual schedule booking in Node.js backend
 		for _, scheduleBooking := range booking.PackageScheduleBookings {
 			if scheduleBooking.NodeBookingID != nil {
@@ -416,9 +482,39 @@ func (s *HolidayPackageService) CancelPackageBooking(id uint) error {
 		}
 
 		// Update main booking status
-		booking.BookingStatus = "cancelled"
-		return tx.Save(&booking).Error
+		updates := map[string]interface{}{"booking_status": "cancelled"}
+		if booking.PaymentStatus == "paid" {
+			updates["payment_status"] = "refunded"
+			updates["refund_amount"] = quote.RefundAmount
+		}
+		return tx.Model(&models.PackageBooking{}).Where("id = ?", booking.ID).Updates(updates).Error
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return quote, nil
+}
+
+// PreviewPackageCancellation returns the refund a package booking would get if it were cancelled now
+func (s *HolidayPackageService) PreviewPackageCancellation(id uint) (*CancellationQuote, error) {
+	var booking models.PackageBooking
+	if err := s.db.First(&booking, id).Error; err != nil {
+		return nil, err
+	}
+
+	if booking.BookingStatus != "pending" && booking.BookingStatus != "confirmed" {
+		return nil, &InvalidTransitionError{From: booking.BookingStatus, To: "cancelled"}
+	}
+	return quotePackageCancellation(&booking, time.Now()), nil
+}
+
+func quotePackageCancellation(booking *models.PackageBooking, now time.Time) *CancellationQuote {
+	paid := 0.0
+	if booking.PaymentStatus == "paid" {
+		paid = booking.TotalAmount
+	}
+	return quoteCancellation(booking.CancellationRules, dateOnly(booking.TravelDate), paid, now)
 }
 
 // cancelIndividualSchedule cancels a single booking in Node.js backend
@@ -448,34 +544,82 @@ func (s *HolidayPackageService) cancelIndividualSchedule(nodeBookingID int, book
 
 	return nil
 }
-// UpdateBookingPaymentStatus updates the payment status and booking status after successful payment
+// UpdateBookingPaymentStatus updates the payment status and booking status after successful payment.
+// Only a pending booking can be confirmed: the booking row is locked so the expiry job cannot free
+// its seats in between, and an expired or cancelled booking, whose seats may have been sold since,
+// fails with an InvalidTransitionError.
 func (s *HolidayPackageService) UpdateBookingPaymentStatus(bookingID uint, paymentID, paymentMethod string) error {
 	return s.db.Transaction(func(tx *gorm.DB) error {
-		// Get the booking
 		var booking models.PackageBooking
-		if err := tx.Preload("PackageScheduleBookings").First(&booking, bookingID).Error; err != nil {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "booking_status").First(&booking, bookingID).Error; err != nil {
 			return err
 		}
+		if booking.BookingStatus != "pending" {
+			return &InvalidTransitionError{From: booking.BookingStatus, To: "confirmed"}
+		}
 
-		// Update payment details
-		booking.PaymentStatus = "paid"
-		booking.BookingStatus = "confirmed"
-		booking.PaymentID = paymentID
-		booking.PaymentMethod = paymentMethod
-
-		// Save the booking
-		if err := tx.Save(&booking).Error; err != nil {
+		if err := tx.Model(&models.PackageBooking{}).Where("id = ?", booking.ID).Updates(map[string]interface{}{
+			"booking_status": "confirmed",
+			"payment_status": "paid",
+			"payment_id":     paymentID,
+			"payment_method": paymentMethod,
+		}).Error; err != nil {
 			return err
 		}
 
 		// Update all schedule bookings to confirmed
-		for _, scheduleBooking := range booking.PackageScheduleBookings {
-			scheduleBooking.BookingStatus = "confirmed"
-			if err := tx.Save(&scheduleBooking).Error; err != nil {
-				return err
-			}
+		return tx.Model(&models.PackageScheduleBooking{}).
+			Where("package_booking_id = ?", booking.ID).
+			Update("booking_status", "confirmed").Error
+	})
+}
+
+// ExpirePendingBookings expires up to batchSize package bookings that are still unpaid after ttl.
+// Their schedule bookings are cancelled so the seats count as free again. Unpaid bookings were never
+// sent to the Node.js backend, so there is nothing to cancel there. Rows are claimed with SKIP LOCKED
+// so several server replicas can run this at the same time.
+func (s *HolidayPackageService) ExpirePendingBookings(ttl time.Duration, batchSize int) (int, error) {
+	cutoff := time.Now().Add(-ttl)
+	reason := fmt.Sprintf("Payment not received within %s", ttl)
+
+	expired := 0
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var bookings []models.PackageBooking
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("booking_status = ? AND payment_status = ? AND created_at < ?", "pending", "pending", cutoff).
+			Order("id").Limit(batchSize).Find(&bookings).Error; err != nil {
+			return err
+		}
+		if len(bookings) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, len(bookings))
+		for i, booking := range bookings {
+			ids[i] = booking.ID
+		}
+
+		if err := tx.Model(&models.PackageScheduleBooking{}).
+			Where("package_booking_id IN ?", ids).
+			Update("booking_status", "cancelled").Error; err != nil {
+			return err
 		}
 
+		if err := tx.Model(&models.PackageBooking{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"booking_status": "expired",
+				"status_reason":  reason,
+			}).Error; err != nil {
+			return err
+		}
+
+		expired = len(ids)
 		return nil
 	})
-}
\ No newline at end of file
+	if err != nil {
+		return 0, err
+	}
+
+	return expired, nil
+}
@@ -0,0 +1,494 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flyola-services/internal/models"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxICalFeedBytes caps the size of an imported iCal feed
+const maxICalFeedBytes = 5 << 20
+
+// ErrInvalidFeedToken is returned when a calendar export is requested without its room's token
+var ErrInvalidFeedToken = errors.New("invalid calendar token")
+
+// ICalConflict is a night an external calendar blocks although it is booked with us
+type ICalConflict struct {
+	Date             string `json:"date"`
+	BookingID        uint   `json:"bookingId"`
+	BookingReference string `json:"bookingReference"`
+}
+
+// ICalSyncResult summarises an import of an external calendar
+type ICalSyncResult struct {
+	FeedID    uint           `json:"feedId"`
+	Events    int            `json:"events"`
+	Blocked   int            `json:"blocked"`  // Nights newly marked unavailable
+	Released  int            `json:"released"` // Nights the feed no longer blocks
+	Conflicts []ICalConflict `json:"conflicts"`
+	Skipped   bool           `json:"skipped,omitempty"` // Another replica was syncing the feed
+}
+
+// icalEvent is the stay of one VEVENT, from Start up to End (exclusive)
+type icalEvent struct {
+	Start time.Time
+	End   time.Time
+}
+
+type ICalService struct {
+	db          *gorm.DB
+	client      *http.Client
+	feedSecret  string
+	horizonDays int
+}
+
+// NewICalService creates the iCal import/export service. When feedSecret is set, room calendar
+// exports require the token returned by ExportToken. Imports cover the next horizonDays nights.
+func NewICalService(db *gorm.DB, feedSecret string, horizonDays int) *ICalService {
+	return &ICalService{
+		db:          db,
+		client:      &http.Client{Timeout: 30 * time.Second},
+		feedSecret:  feedSecret,
+		horizonDays: horizonDays,
+	}
+}
+
+// ExportToken returns the token a room's calendar export must be requested with, or "" when
+// exports are not protected
+func (s *ICalService) ExportToken(roomID uint) string {
+	if s.feedSecret == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(s.feedSecret))
+	fmt.Fprintf(mac, "room:%d", roomID)
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// ExportRoomCalendar renders the booked and blocked nights of a room from today on as an
+// iCalendar feed. Bookings are exported one event each and other blocked nights as ranges; no
+// guest details are included.
+func (s *ICalService) ExportRoomCalendar(roomID uint, token string) ([]byte, error) {
+	if expected := s.ExportToken(roomID); expected != "" && !hmac.Equal([]byte(token), []byte(expected)) {
+		return nil, ErrInvalidFeedToken
+	}
+
+	var room models.Room
+	if err := s.db.Select("id").First(&room, roomID).Error; err != nil {
+		return nil, err
+	}
+
+	from := dateOnly(time.Now())
+	to := from.AddDate(0, 0, s.horizonDays)
+
+	var bookings []models.HotelBooking
+	if err := s.db.Select("id", "check_in_date", "check_out_date").
+		Where("room_id = ? AND booking_status IN ?", roomID, inventoryHoldingStatuses).
+		Where("check_out_date > ? AND check_in_date < ?", from, to).
+		Order("check_in_date").Find(&bookings).Error; err != nil {
+		return nil, err
+	}
+	booked := make(map[string]bool)
+	for _, booking := range bookings {
+		for _, night := range stayNights(dateOnly(booking.CheckInDate), dateOnly(booking.CheckOutDate)) {
+			booked[night.Format("2006-01-02")] = true
+		}
+	}
+
+	var blocked []models.RoomAvailability
+	if err := s.db.Select("date").
+		Where("room_id = ? AND is_available = ? AND date >= ? AND date < ?", roomID, false, from, to).
+		Order("date").Find(&blocked).Error; err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	writeLine := func(line string) { b.WriteString(line + "\r\n") }
+	writeEvent := func(uid, summary string, start, end time.Time) {
+		writeLine("BEGIN:VEVENT")
+		writeLine("UID:" + uid)
+		writeLine("DTSTAMP:" + stamp)
+		writeLine("DTSTART;VALUE=DATE:" + start.Format("20060102"))
+		writeLine("DTEND;VALUE=DATE:" + end.Format("20060102"))
+		writeLine("SUMMARY:" + summary)
+		writeLine("END:VEVENT")
+	}
+
+	writeLine("BEGIN:VCALENDAR")
+	writeLine("VERSION:2.0")
+	writeLine("PRODID:-//Flyola//Hotel Inventory//EN")
+	writeLine("CALSCALE:GREGORIAN")
+	writeLine("METHOD:PUBLISH")
+	for _, booking := range bookings {
+		writeEvent(fmt.Sprintf("booking-%d@flyola", booking.ID), "Reserved",
+			dateOnly(booking.CheckInDate), dateOnly(booking.CheckOutDate))
+	}
+
+	// Consecutive blocked nights that are not part of a booking become one event
+	var start, end time.Time
+	flush := func() {
+		if !start.IsZero() {
+			writeEvent(fmt.Sprintf("block-%d-%s@flyola", roomID, start.Format("20060102")), "Not available", start, end)
+		}
+	}
+	for _, availability := range blocked {
+		night := dateOnly(availability.Date)
+		if booked[night.Format("2006-01-02")] {
+			continue
+		}
+		if !start.IsZero() && night.Equal(end) {
+			end = night.AddDate(0, 0, 1)
+			continue
+		}
+		flush()
+		start, end = night, night.AddDate(0, 0, 1)
+	}
+	flush()
+	writeLine("END:VCALENDAR")
+
+	return []byte(b.String()), nil
+}
+
+func (s *ICalService) GetRoomFeeds(roomID uint) ([]models.ICalFeed, error) {
+	var feeds []models.ICalFeed
+	err := s.db.Where("room_id = ?", roomID).Order("id").Find(&feeds).Error
+	return feeds, err
+}
+
+func (s *ICalService) CreateFeed(feed *models.ICalFeed) error {
+	if strings.TrimSpace(feed.Name) == "" {
+		return &BookingValidationError{Message: "name is required"}
+	}
+	parsed, err := url.Parse(feed.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return &BookingValidationError{Message: "url must be an http or https URL"}
+	}
+	if err := s.db.Select("id").First(&models.Room{}, feed.RoomID).Error; err != nil {
+		return err
+	}
+	return s.db.Create(feed).Error
+}
+
+// DeleteFeed removes a feed and reopens the nights it blocked
+func (s *ICalService) DeleteFeed(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var feed models.ICalFeed
+		if err := tx.First(&feed, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.RoomAvailability{}).
+			Where("room_id = ? AND block_source = ?", feed.RoomID, feed.BlockSource()).
+			Updates(map[string]interface{}{"is_available": true, "block_source": nil}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&feed).Error
+	})
+}
+
+// SyncFeed imports a feed: nights it lists are blocked on the room and nights it no longer lists
+// are reopened. Nights it lists that are booked with us are left alone and reported as conflicts.
+func (s *ICalService) SyncFeed(ctx context.Context, id uint) (*ICalSyncResult, error) {
+	var feed models.ICalFeed
+	if err := s.db.First(&feed, id).Error; err != nil {
+		return nil, err
+	}
+
+	events, err := s.fetchFeed(ctx, feed.URL)
+	if err != nil {
+		s.db.Model(&feed).Update("last_error", err.Error())
+		return nil, err
+	}
+
+	result, err := s.applyFeed(&feed, events)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Conflicts) > 0 {
+		log.Printf("⚠️  iCal feed %d (%s) blocks %d night(s) of room %d that are booked with us", feed.ID, feed.Name, len(result.Conflicts), feed.RoomID)
+	}
+	return result, nil
+}
+
+// SyncAll imports every active feed. A failing feed is recorded on the feed and does not stop
+// the others; the first error is returned.
+func (s *ICalService) SyncAll(ctx context.Context) error {
+	var feedIDs []uint
+	if err := s.db.Model(&models.ICalFeed{}).Where("status = ?", 0).Order("id").Pluck("id", &feedIDs).Error; err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, id := range feedIDs {
+		if ctx.Err() != nil {
+			break
+		}
+		if _, err := s.SyncFeed(ctx, id); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("feed %d: %w", id, err)
+		}
+	}
+	return firstErr
+}
+
+// fetchFeed downloads and parses an iCal feed
+func (s *ICalService) fetchFeed(ctx context.Context, feedURL string) ([]icalEvent, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/calendar")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("feed returned HTTP %d", resp.StatusCode)
+	}
+	return parseICalEvents(io.LimitReader(resp.Body, maxICalFeedBytes))
+}
+
+// applyFeed blocks and releases the room's nights to match a feed's events within the horizon.
+// The feed row is claimed with SKIP LOCKED so only one replica applies a feed at a time, and the
+// room is locked like a reservation so bookings and imports cannot interleave.
+func (s *ICalService) applyFeed(feed *models.ICalFeed, events []icalEvent) (*ICalSyncResult, error) {
+	result := &ICalSyncResult{FeedID: feed.ID, Events: len(events), Conflicts: []ICalConflict{}}
+	source := feed.BlockSource()
+
+	from := dateOnly(time.Now())
+	to := from.AddDate(0, 0, s.horizonDays)
+	feedNights := make(map[string]time.Time)
+	for _, event := range events {
+		for _, night := range stayNights(event.Start, event.End) {
+			if !night.Before(from) && night.Before(to) {
+				feedNights[night.Format("2006-01-02")] = night
+			}
+		}
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var claimed []models.ICalFeed
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("id = ?", feed.ID).Find(&claimed).Error; err != nil {
+			return err
+		}
+		if len(claimed) == 0 {
+			result.Skipped = true
+			return nil
+		}
+
+		var room models.Room
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "base_price").First(&room, feed.RoomID).Error; err != nil {
+			return err
+		}
+
+		var bookings []models.HotelBooking
+		if err := tx.Select("id", "booking_reference", "check_in_date", "check_out_date").
+			Where("room_id = ? AND booking_status IN ?", room.ID, inventoryHoldingStatuses).
+			Where("check_in_date < ? AND check_out_date > ?", to, from).
+			Find(&bookings).Error; err != nil {
+			return err
+		}
+		bookedBy := make(map[string]*models.HotelBooking)
+		for i := range bookings {
+			for _, night := range stayNights(dateOnly(bookings[i].CheckInDate), dateOnly(bookings[i].CheckOutDate)) {
+				bookedBy[night.Format("2006-01-02")] = &bookings[i]
+			}
+		}
+
+		var availabilities []models.RoomAvailability
+		if err := tx.Where("room_id = ? AND date >= ? AND date < ?", room.ID, from, to).
+			Find(&availabilities).Error; err != nil {
+			return err
+		}
+		rows := make(map[string]models.RoomAvailability, len(availabilities))
+		for _, availability := range availabilities {
+			rows[availability.Date.Format("2006-01-02")] = availability
+		}
+
+		keys := make([]string, 0, len(feedNights))
+		for key := range feedNights {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		var blockIDs []uint
+		var missing []models.RoomAvailability
+		for _, key := range keys {
+			if booking, ok := bookedBy[key]; ok {
+				result.Conflicts = append(result.Conflicts, ICalConflict{
+					Date:             key,
+					BookingID:        booking.ID,
+					BookingReference: booking.BookingReference,
+				})
+				continue
+			}
+			row, ok := rows[key]
+			if !ok {
+				missing = append(missing, models.RoomAvailability{
+					RoomID:      room.ID,
+					Date:        feedNights[key],
+					IsAvailable: false,
+					Price:       room.BasePrice,
+					PriceSource: models.PriceSourceBase,
+					BlockSource: source,
+				})
+				continue
+			}
+			// Nights closed by hand or by another feed are left to their owner
+			if row.IsAvailable {
+				blockIDs = append(blockIDs, row.ID)
+			}
+		}
+
+		var releaseIDs []uint
+		for key, row := range rows {
+			if row.BlockSource != source {
+				continue
+			}
+			if _, listed := feedNights[key]; listed {
+				continue
+			}
+			if _, isBooked := bookedBy[key]; isBooked {
+				continue
+			}
+			releaseIDs = append(releaseIDs, row.ID)
+		}
+
+		if len(blockIDs) > 0 {
+			if err := tx.Model(&models.RoomAvailability{}).Where("id IN ?", blockIDs).
+				Updates(map[string]interface{}{"is_available": false, "block_source": source}).Error; err != nil {
+				return err
+			}
+		}
+		if len(missing) > 0 {
+			if err := tx.Omit("Room").CreateInBatches(&missing, bulkWriteBatch).Error; err != nil {
+				return err
+			}
+		}
+		if len(releaseIDs) > 0 {
+			if err := tx.Model(&models.RoomAvailability{}).Where("id IN ?", releaseIDs).
+				Updates(map[string]interface{}{"is_available": true, "block_source": nil}).Error; err != nil {
+				return err
+			}
+		}
+		result.Blocked = len(blockIDs) + len(missing)
+		result.Released = len(releaseIDs)
+
+		conflicts, err := json.Marshal(result.Conflicts)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		return tx.Model(feed).Updates(map[string]interface{}{
+			"last_synced_at": now,
+			"last_error":     "",
+			"last_conflicts": conflicts,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// parseICalEvents reads the VEVENTs of an iCalendar document. Cancelled events and events
+// without a start are skipped; an event without an end lasts one night.
+func parseICalEvents(r io.Reader) ([]icalEvent, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+
+	// Unfold continuation lines (RFC 5545 3.1) before reading properties
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 || !strings.EqualFold(strings.TrimSpace(lines[0]), "BEGIN:VCALENDAR") {
+		return nil, errors.New("not an iCalendar feed")
+	}
+
+	var events []icalEvent
+	var inEvent, cancelled bool
+	var start, end time.Time
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, params, _ := strings.Cut(strings.ToUpper(name), ";")
+
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			inEvent, cancelled = true, false
+			start, end = time.Time{}, time.Time{}
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			if inEvent && !cancelled && !start.IsZero() {
+				if !end.After(start) {
+					end = start.AddDate(0, 0, 1)
+				}
+				events = append(events, icalEvent{Start: start, End: end})
+			}
+			inEvent = false
+		case !inEvent:
+		case name == "STATUS":
+			cancelled = strings.EqualFold(strings.TrimSpace(value), "CANCELLED")
+		case name == "DTSTART" || name == "DTEND":
+			date, err := parseICalDate(strings.TrimSpace(value), params)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %w", name, value, err)
+			}
+			if name == "DTSTART" {
+				start = date
+			} else {
+				end = date
+			}
+		}
+	}
+	return events, nil
+}
+
+// parseICalDate reads a DATE or DATE-TIME value as the local date it falls on. A time of day
+// past midnight on the end date still counts that date as the departure day.
+func parseICalDate(value, params string) (time.Time, error) {
+	if strings.Contains(params, "VALUE=DATE") && !strings.Contains(params, "VALUE=DATE-TIME") || len(value) == 8 {
+		date, err := time.ParseInLocation("20060102", value, time.Local)
+		return date, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		date, err := time.Parse("20060102T150405Z", value)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return dateOnly(date.In(time.Local)), nil
+	}
+	date, err := time.ParseInLocation("20060102T150405", value, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return dateOnly(date), nil
+}
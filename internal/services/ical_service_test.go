@@ -0,0 +1,277 @@
+package services
+
+import (
+	"context"
+	"flyola-services/internal/models"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// testFeed serves an iCalendar document whose events can be changed between syncs
+type testFeed struct {
+	mu     sync.Mutex
+	events []string
+	server *httptest.Server
+}
+
+func newTestFeed(t *testing.T) *testFeed {
+	feed := &testFeed{}
+	feed.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		feed.mu.Lock()
+		defer feed.mu.Unlock()
+		w.Header().Set("Content-Type", "text/calendar")
+		fmt.Fprint(w, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Test//Feed//EN\r\n")
+		for _, event := range feed.events {
+			fmt.Fprint(w, event)
+		}
+		fmt.Fprint(w, "END:VCALENDAR\r\n")
+	}))
+	t.Cleanup(feed.server.Close)
+	return feed
+}
+
+// setEvents replaces the events the feed serves
+func (f *testFeed) setEvents(events ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = events
+}
+
+// icsEvent formats an all-day VEVENT from start up to end; status may be empty
+func icsEvent(uid string, start, end time.Time, status string) string {
+	var b strings.Builder
+	b.WriteString("BEGIN:VEVENT\r\n")
+	fmt.Fprintf(&b, "UID:%s\r\n", uid)
+	fmt.Fprintf(&b, "DTSTART;VALUE=DATE:%s\r\n", start.Format("20060102"))
+	fmt.Fprintf(&b, "DTEND;VALUE=DATE:%s\r\n", end.Format("20060102"))
+	if status != "" {
+		fmt.Fprintf(&b, "STATUS:%s\r\n", status)
+	}
+	b.WriteString("SUMMARY:Reserved\r\nEND:VEVENT\r\n")
+	return b.String()
+}
+
+// createTestFeed registers the feed server as an import feed of the test hotel's room
+func createTestFeed(t *testing.T, service *ICalService, fixture *testHotel, feed *testFeed) models.ICalFeed {
+	t.Helper()
+	icalFeed := models.ICalFeed{RoomID: fixture.Room.ID, Name: "Test OTA", URL: feed.server.URL + "/calendar.ics"}
+	if err := service.CreateFeed(&icalFeed); err != nil {
+		t.Fatalf("create feed: %v", err)
+	}
+	return icalFeed
+}
+
+func TestParseICalEventsSkipsCancelled(t *testing.T) {
+	start := time.Date(2030, 3, 10, 0, 0, 0, 0, time.Local)
+	doc := "BEGIN:VCALENDAR\r\n" +
+		icsEvent("kept", start, start.AddDate(0, 0, 2), "CONFIRMED") +
+		icsEvent("cancelled", start.AddDate(0, 0, 5), start.AddDate(0, 0, 7), "CANCELLED") +
+		"END:VCALENDAR\r\n"
+
+	events, err := parseICalEvents(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	if !events[0].Start.Equal(start) || !events[0].End.Equal(start.AddDate(0, 0, 2)) {
+		t.Errorf("event = %v..%v, want %v..%v", events[0].Start, events[0].End, start, start.AddDate(0, 0, 2))
+	}
+}
+
+func TestICalSyncBlocksFeedNights(t *testing.T) {
+	db := testDB(t)
+	fixture := createTestHotel(t, db)
+	service := NewICalService(db, "", 365)
+	feed := newTestFeed(t)
+	icalFeed := createTestFeed(t, service, fixture, feed)
+
+	checkIn, checkOut := daysFromToday(10), daysFromToday(13)
+	feed.setEvents(icsEvent("stay-1", checkIn, checkOut, ""))
+
+	result, err := service.SyncFeed(context.Background(), icalFeed.ID)
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if result.Events != 1 || result.Blocked != 3 || result.Released != 0 || len(result.Conflicts) != 0 {
+		t.Fatalf("result = %+v, want 1 event and 3 blocked nights", result)
+	}
+
+	nights := roomNights(t, db, fixture.Room.ID, checkIn, checkOut)
+	for _, night := range stayNights(checkIn, checkOut) {
+		row, ok := nights[night.Format("2006-01-02")]
+		if !ok || row.IsAvailable || row.BlockSource != icalFeed.BlockSource() {
+			t.Errorf("night %s = %+v, want blocked by %s", night.Format("2006-01-02"), row, icalFeed.BlockSource())
+		}
+	}
+
+	// Syncing the same feed again changes nothing
+	result, err = service.SyncFeed(context.Background(), icalFeed.ID)
+	if err != nil {
+		t.Fatalf("resync: %v", err)
+	}
+	if result.Blocked != 0 || result.Released != 0 {
+		t.Errorf("resync result = %+v, want no changes", result)
+	}
+}
+
+func TestICalSyncReleasesRemovedEvents(t *testing.T) {
+	db := testDB(t)
+	fixture := createTestHotel(t, db)
+	service := NewICalService(db, "", 365)
+	feed := newTestFeed(t)
+	icalFeed := createTestFeed(t, service, fixture, feed)
+
+	kept, removed := daysFromToday(5), daysFromToday(20)
+	feed.setEvents(
+		icsEvent("kept", kept, kept.AddDate(0, 0, 2), ""),
+		icsEvent("removed", removed, removed.AddDate(0, 0, 2), ""),
+	)
+	if _, err := service.SyncFeed(context.Background(), icalFeed.ID); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+
+	// A night closed by hand next to the feed's nights must survive the release
+	manual := removed.AddDate(0, 0, 2)
+	if err := db.Create(&models.RoomAvailability{
+		RoomID:      fixture.Room.ID,
+		Date:        manual,
+		IsAvailable: false,
+		Price:       fixture.Room.BasePrice,
+		PriceSource: models.PriceSourceBase,
+		BlockSource: models.BlockSourceManual,
+	}).Error; err != nil {
+		t.Fatalf("close night by hand: %v", err)
+	}
+
+	feed.setEvents(icsEvent("kept", kept, kept.AddDate(0, 0, 2), ""))
+	result, err := service.SyncFeed(context.Background(), icalFeed.ID)
+	if err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+	if result.Released != 2 || result.Blocked != 0 {
+		t.Fatalf("result = %+v, want 2 released nights", result)
+	}
+
+	nights := roomNights(t, db, fixture.Room.ID, removed, manual.AddDate(0, 0, 1))
+	for _, night := range stayNights(removed, removed.AddDate(0, 0, 2)) {
+		row := nights[night.Format("2006-01-02")]
+		if !row.IsAvailable || row.BlockSource != "" {
+			t.Errorf("night %s = %+v, want reopened", night.Format("2006-01-02"), row)
+		}
+	}
+	if row := nights[manual.Format("2006-01-02")]; row.IsAvailable || row.BlockSource != models.BlockSourceManual {
+		t.Errorf("manually closed night = %+v, want still closed", row)
+	}
+
+	keptNights := roomNights(t, db, fixture.Room.ID, kept, kept.AddDate(0, 0, 2))
+	for key, row := range keptNights {
+		if row.IsAvailable {
+			t.Errorf("night %s of the remaining event was reopened", key)
+		}
+	}
+}
+
+func TestICalSyncIgnoresCancelledEvents(t *testing.T) {
+	db := testDB(t)
+	fixture := createTestHotel(t, db)
+	service := NewICalService(db, "", 365)
+	feed := newTestFeed(t)
+	icalFeed := createTestFeed(t, service, fixture, feed)
+
+	start := daysFromToday(8)
+	feed.setEvents(icsEvent("stay-1", start, start.AddDate(0, 0, 2), ""))
+	if _, err := service.SyncFeed(context.Background(), icalFeed.ID); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+
+	// The OTA cancels the stay and publishes a new one that is cancelled from the start
+	other := daysFromToday(30)
+	feed.setEvents(
+		icsEvent("stay-1", start, start.AddDate(0, 0, 2), "CANCELLED"),
+		icsEvent("stay-2", other, other.AddDate(0, 0, 3), "CANCELLED"),
+	)
+	result, err := service.SyncFeed(context.Background(), icalFeed.ID)
+	if err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+	if result.Events != 0 || result.Blocked != 0 || result.Released != 2 {
+		t.Fatalf("result = %+v, want no events and 2 released nights", result)
+	}
+
+	for key, row := range roomNights(t, db, fixture.Room.ID, start, other.AddDate(0, 0, 3)) {
+		if !row.IsAvailable {
+			t.Errorf("night %s is still blocked by a cancelled event", key)
+		}
+	}
+}
+
+func TestICalSyncReportsConflictsWithBookings(t *testing.T) {
+	db := testDB(t)
+	fixture := createTestHotel(t, db)
+	service := NewICalService(db, "", 365)
+	feed := newTestFeed(t)
+	icalFeed := createTestFeed(t, service, fixture, feed)
+
+	checkIn, checkOut := daysFromToday(15), daysFromToday(17)
+	booking := createTestBooking(t, db, fixture, checkIn, checkOut)
+
+	// The feed's event covers the booked nights and one free night after them
+	feed.setEvents(icsEvent("overlap", checkIn, checkOut.AddDate(0, 0, 1), ""))
+	result, err := service.SyncFeed(context.Background(), icalFeed.ID)
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if result.Blocked != 1 {
+		t.Errorf("blocked = %d, want 1", result.Blocked)
+	}
+	if len(result.Conflicts) != 2 {
+		t.Fatalf("conflicts = %+v, want the 2 booked nights", result.Conflicts)
+	}
+	for i, night := range stayNights(checkIn, checkOut) {
+		conflict := result.Conflicts[i]
+		if conflict.Date != night.Format("2006-01-02") || conflict.BookingID != booking.ID || conflict.BookingReference != booking.BookingReference {
+			t.Errorf("conflict %d = %+v, want %s of booking %s", i, conflict, night.Format("2006-01-02"), booking.BookingReference)
+		}
+	}
+
+	// The booked nights stay the booking's, and the conflicts are recorded on the feed
+	for key, row := range roomNights(t, db, fixture.Room.ID, checkIn, checkOut) {
+		if row.BlockSource != models.BlockSourceBooking {
+			t.Errorf("booked night %s = %+v, want still held by the booking", key, row)
+		}
+	}
+	var stored models.ICalFeed
+	if err := db.First(&stored, icalFeed.ID).Error; err != nil {
+		t.Fatalf("reload feed: %v", err)
+	}
+	if !strings.Contains(string(stored.LastConflicts), booking.BookingReference) {
+		t.Errorf("last_conflicts = %s, want it to list %s", stored.LastConflicts, booking.BookingReference)
+	}
+
+	// Once the booking is cancelled and its nights released, the next sync blocks them
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := releaseRoomNights(tx, fixture.Room.ID, checkIn, checkOut); err != nil {
+			return err
+		}
+		return tx.Model(&booking).Update("booking_status", models.BookingStatusCancelled).Error
+	})
+	if err != nil {
+		t.Fatalf("cancel booking: %v", err)
+	}
+	result, err = service.SyncFeed(context.Background(), icalFeed.ID)
+	if err != nil {
+		t.Fatalf("resync: %v", err)
+	}
+	if len(result.Conflicts) != 0 || result.Blocked != 2 {
+		t.Errorf("resync result = %+v, want the 2 freed nights blocked without conflicts", result)
+	}
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"errors"
+	"flyola-services/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// IdempotencyMismatchError is returned when an idempotency key is reused for a different request
+type IdempotencyMismatchError struct{}
+
+func (e *IdempotencyMismatchError) Error() string {
+	return "idempotency key was already used with a different request"
+}
+
+// IdempotencyInProgressError is returned when the first request made with a key is still running
+type IdempotencyInProgressError struct{}
+
+func (e *IdempotencyInProgressError) Error() string {
+	return "a request with this idempotency key is still being processed"
+}
+
+type IdempotencyService struct {
+	db  *gorm.DB
+	ttl time.Duration
+}
+
+// NewIdempotencyService creates the store of idempotency keys. Keys are remembered for ttl.
+func NewIdempotencyService(db *gorm.DB, ttl time.Duration) *IdempotencyService {
+	return &IdempotencyService{db: db, ttl: ttl}
+}
+
+// Begin claims key for a request to scope with the given fingerprint. The first request gets a new
+// processing record and replay false. A retry of a completed request gets the stored record and
+// replay true. The unique index on key and scope decides between concurrent first requests.
+func (s *IdempotencyService) Begin(key, scope, fingerprint string) (*models.IdempotencyKey, bool, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		record := &models.IdempotencyKey{
+			Key:         key,
+			Scope:       scope,
+			Fingerprint: fingerprint,
+			Status:      models.IdempotencyStatusProcessing,
+		}
+		err := s.db.Create(record).Error
+		if err == nil {
+			return record, false, nil
+		}
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, false, err
+		}
+
+		var existing models.IdempotencyKey
+		err = s.db.Where("`key` = ? AND scope = ?", key, scope).First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// The first request failed and let go of the key in the meantime
+			continue
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		if existing.CreatedAt.Before(time.Now().Add(-s.ttl)) {
+			// A key past its retention that has not been purged yet starts afresh
+			if err := s.db.Delete(&existing).Error; err != nil {
+				return nil, false, err
+			}
+			continue
+		}
+		if existing.Fingerprint != fingerprint {
+			return nil, false, &IdempotencyMismatchError{}
+		}
+		if existing.Status != models.IdempotencyStatusCompleted {
+			return nil, false, &IdempotencyInProgressError{}
+		}
+		return &existing, true, nil
+	}
+	return nil, false, &IdempotencyInProgressError{}
+}
+
+// Complete stores the response of the request that claimed record, to be replayed to retries
+func (s *IdempotencyService) Complete(record *models.IdempotencyKey, statusCode int, contentType string, body []byte) error {
+	return s.db.Model(record).Updates(map[string]interface{}{
+		"status":       models.IdempotencyStatusCompleted,
+		"status_code":  statusCode,
+		"content_type": contentType,
+		"response":     body,
+	}).Error
+}
+
+// Release forgets a key whose request failed, so the client can retry it
+func (s *IdempotencyService) Release(record *models.IdempotencyKey) error {
+	return s.db.Delete(record).Error
+}
+
+// PurgeExpired deletes up to batchSize keys older than the retention period
+func (s *IdempotencyService) PurgeExpired(batchSize int) (int, error) {
+	var ids []uint
+	if err := s.db.Model(&models.IdempotencyKey{}).
+		Where("created_at < ?", time.Now().Add(-s.ttl)).
+		Order("id").Limit(batchSize).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := s.db.Where("id IN ?", ids).Delete(&models.IdempotencyKey{})
+	return int(result.RowsAffected), result.Error
+}
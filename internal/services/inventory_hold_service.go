@@ -0,0 +1,379 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flyola-services/internal/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxHoldMinutes caps how long a guest may ask for inventory to be held
+const maxHoldMinutes = 30
+
+// HoldUnavailableError is returned when a hold token has already been used, released or expired
+type HoldUnavailableError struct {
+	Status string
+}
+
+func (e *HoldUnavailableError) Error() string {
+	return fmt.Sprintf("hold is %s", e.Status)
+}
+
+// PackageSeatsError is returned when a holiday package departure has fewer free seats than asked for
+type PackageSeatsError struct {
+	PackageID  uint
+	TravelDate string
+	Requested  int
+	Available  int
+}
+
+func (e *PackageSeatsError) Error() string {
+	return fmt.Sprintf("package %d has %d seat(s) left on %s, %d requested", e.PackageID, e.Available, e.TravelDate, e.Requested)
+}
+
+// HoldRequest asks for inventory to be held during checkout: the nights of a room (RoomID), a room
+// of a category's allotment (HotelID and RoomCategoryID), or seats on a holiday package departure
+// (PackageID and TravelDate). Minutes defaults to the service's hold TTL.
+type HoldRequest struct {
+	RoomID         *uint     `json:"room_id"`
+	HotelID        *uint     `json:"hotel_id"`
+	RoomCategoryID *uint     `json:"room_category_id"`
+	CheckInDate    time.Time `json:"check_in_date"`
+	CheckOutDate   time.Time `json:"check_out_date"`
+	PackageID      *uint     `json:"package_id"`
+	TravelDate     string    `json:"travel_date"`
+	Seats          int       `json:"seats"`
+	Minutes        int       `json:"minutes"`
+}
+
+type HoldService struct {
+	db  *gorm.DB
+	ttl time.Duration
+}
+
+func NewHoldService(db *gorm.DB, ttl time.Duration) *HoldService {
+	return &HoldService{db: db, ttl: ttl}
+}
+
+// CreateHold reserves the inventory of request and returns the hold with its token. Room nights and
+// allotments are taken exactly as a booking takes them, so concurrent holds and bookings for the
+// same inventory are serialised by the same row locks.
+func (s *HoldService) CreateHold(request *HoldRequest) (*models.InventoryHold, error) {
+	ttl := s.ttl
+	if request.Minutes != 0 {
+		if request.Minutes < 0 || request.Minutes > maxHoldMinutes {
+			return nil, &BookingValidationError{Message: fmt.Sprintf("minutes must be between 1 and %d", maxHoldMinutes)}
+		}
+		ttl = time.Duration(request.Minutes) * time.Minute
+	}
+
+	token, err := newHoldToken()
+	if err != nil {
+		return nil, err
+	}
+	hold := &models.InventoryHold{Token: token, Status: models.HoldStatusActive}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		switch {
+		case request.PackageID != nil:
+			err = holdPackageSeats(tx, hold, request)
+		case request.RoomID != nil || request.RoomCategoryID != nil:
+			err = holdStay(tx, hold, request)
+		default:
+			err = &BookingValidationError{Message: "room_id, room_category_id or package_id is required"}
+		}
+		if err != nil {
+			return err
+		}
+
+		// The hold runs from the moment its inventory is secured
+		hold.ExpiresAt = time.Now().Add(ttl)
+		return tx.Create(hold).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// GetHold returns a hold by its token
+func (s *HoldService) GetHold(token string) (*models.InventoryHold, error) {
+	var hold models.InventoryHold
+	if err := s.db.Where("token = ?", token).First(&hold).Error; err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// ReleaseHold gives the inventory of an active hold back, for a guest who abandons checkout
+func (s *HoldService) ReleaseHold(token string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var hold models.InventoryHold
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("token = ?", token).First(&hold).Error; err != nil {
+			return err
+		}
+		if hold.Status != models.HoldStatusActive {
+			return &HoldUnavailableError{Status: hold.Status}
+		}
+		if err := releaseHoldInventory(tx, &hold); err != nil {
+			return err
+		}
+		return tx.Model(&hold).Update("status", models.HoldStatusReleased).Error
+	})
+}
+
+// ExpireHolds releases up to batchSize active holds whose time is up. Rows are claimed with SKIP
+// LOCKED so several server replicas can run this at the same time, and a hold being consumed by a
+// booking is left alone.
+func (s *HoldService) ExpireHolds(batchSize int) (int, error) {
+	expired := 0
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var holds []models.InventoryHold
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND expires_at < ?", models.HoldStatusActive, time.Now()).
+			Order("id").Limit(batchSize).Find(&holds).Error; err != nil {
+			return err
+		}
+		if len(holds) == 0 {
+			return nil
+		}
+
+		// Lock the batch's rooms in ID order up front, so sweepers running at the same time
+		// cannot lock them in opposite orders
+		var roomIDs []uint
+		for _, hold := range holds {
+			if hold.InventoryType == models.InventoryTypeRoom {
+				roomIDs = append(roomIDs, *hold.RoomID)
+			}
+		}
+		if len(roomIDs) > 0 {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+				Where("id IN ?", roomIDs).Order("id").Find(&[]models.Room{}).Error; err != nil {
+				return err
+			}
+		}
+
+		ids := make([]uint, len(holds))
+		for i := range holds {
+			if err := releaseHoldInventory(tx, &holds[i]); err != nil {
+				return err
+			}
+			ids[i] = holds[i].ID
+		}
+
+		if err := tx.Model(&models.InventoryHold{}).Where("id IN ?", ids).
+			Update("status", models.HoldStatusExpired).Error; err != nil {
+			return err
+		}
+		expired = len(ids)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return expired, nil
+}
+
+// holdStay reserves the nights of a stay in a room, or in a room of a category's allotment
+func holdStay(tx *gorm.DB, hold *models.InventoryHold, request *HoldRequest) error {
+	checkIn, checkOut := dateOnly(request.CheckInDate), dateOnly(request.CheckOutDate)
+	if !checkOut.After(checkIn) {
+		return &BookingValidationError{Message: "check_out_date must be after check_in_date"}
+	}
+	if checkIn.Before(dateOnly(time.Now())) {
+		return &BookingValidationError{Message: "check_in_date cannot be in the past"}
+	}
+	hold.CheckInDate = &checkIn
+	hold.CheckOutDate = &checkOut
+
+	if request.RoomID != nil {
+		var room models.Room
+		err := tx.Select("id", "hotel_id", "room_category_id").First(&room, *request.RoomID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &BookingValidationError{Message: "Room not found"}
+		}
+		if err != nil {
+			return err
+		}
+
+		hold.InventoryType = models.InventoryTypeRoom
+		hold.RoomID = &room.ID
+		hold.HotelID = &room.HotelID
+		hold.RoomCategoryID = &room.RoomCategoryID
+		return reserveRoomNights(tx, room.ID, checkIn, checkOut, 0)
+	}
+
+	if request.HotelID == nil {
+		return &BookingValidationError{Message: "hotel_id is required with room_category_id"}
+	}
+	hold.InventoryType = models.InventoryTypeAllotment
+	hold.HotelID = request.HotelID
+	hold.RoomCategoryID = request.RoomCategoryID
+	return reserveAllotmentNights(tx, *request.HotelID, *request.RoomCategoryID, checkIn, checkOut)
+}
+
+// holdPackageSeats keeps seats aside on a holiday package departure
+func holdPackageSeats(tx *gorm.DB, hold *models.InventoryHold, request *HoldRequest) error {
+	if request.Seats < 1 {
+		return &BookingValidationError{Message: "seats must be at least 1"}
+	}
+	travelDate, err := time.ParseInLocation("2006-01-02", request.TravelDate, time.Local)
+	if err != nil {
+		return &BookingValidationError{Message: "travel_date is required (YYYY-MM-DD)"}
+	}
+	if travelDate.Before(dateOnly(time.Now())) {
+		return &BookingValidationError{Message: "travel_date cannot be in the past"}
+	}
+
+	if err := checkPackageSeats(tx, *request.PackageID, travelDate, request.Seats); err != nil {
+		return err
+	}
+
+	hold.InventoryType = models.InventoryTypePackage
+	hold.PackageID = request.PackageID
+	hold.TravelDate = &travelDate
+	hold.Seats = request.Seats
+	return nil
+}
+
+// checkPackageSeats locks an active holiday package and checks that a departure still has seats
+// for that many passengers. DepartureCapacity is the number of seats on each departure; seats are
+// taken by pending and confirmed bookings and by active holds. Packages without a capacity are
+// never full.
+func checkPackageSeats(tx *gorm.DB, packageID uint, travelDate time.Time, seats int) error {
+	var pkg models.HolidayPackage
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "departure_capacity").
+		Where("status = ?", 1).First(&pkg, packageID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &BookingValidationError{Message: "Package not found"}
+	}
+	if err != nil || pkg.DepartureCapacity <= 0 {
+		return err
+	}
+
+	date := travelDate.Format("2006-01-02")
+	var booked, held int64
+	if err := tx.Model(&models.PackageBooking{}).
+		Where("package_id = ? AND travel_date = ? AND booking_status IN ?", packageID, date, []string{"pending", "confirmed"}).
+		Select("COALESCE(SUM(num_passengers), 0)").Scan(&booked).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.InventoryHold{}).
+		Where("package_id = ? AND travel_date = ? AND status = ? AND expires_at > ?", packageID, date, models.HoldStatusActive, time.Now()).
+		Select("COALESCE(SUM(seats), 0)").Scan(&held).Error; err != nil {
+		return err
+	}
+
+	available := pkg.DepartureCapacity - int(booked) - int(held)
+	if seats > available {
+		if available < 0 {
+			available = 0
+		}
+		return &PackageSeatsError{PackageID: packageID, TravelDate: date, Requested: seats, Available: available}
+	}
+	return nil
+}
+
+// releaseHoldInventory gives back what a hold reserved. Package seats are counted from active
+// holds, so there is nothing to give back for them.
+func releaseHoldInventory(tx *gorm.DB, hold *models.InventoryHold) error {
+	switch hold.InventoryType {
+	case models.InventoryTypeRoom:
+		// The room is locked before its nights, in the order reserveRoomNights takes them
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.Room{}, *hold.RoomID).Error; err != nil {
+			return err
+		}
+		return releaseRoomNights(tx, *hold.RoomID, *hold.CheckInDate, *hold.CheckOutDate)
+	case models.InventoryTypeAllotment:
+		return releaseAllotmentNights(tx, *hold.HotelID, *hold.RoomCategoryID, *hold.CheckInDate, *hold.CheckOutDate)
+	}
+	return nil
+}
+
+// takeHold locks the hold of a token for a booking about to consume it. A hold whose time is up
+// but that the sweeper has not reached yet is released as expired, and nil is returned so the
+// booking competes for the inventory like any other.
+func takeHold(tx *gorm.DB, token string, inventoryType string) (*models.InventoryHold, error) {
+	var hold models.InventoryHold
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("token = ?", token).First(&hold).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, &BookingValidationError{Message: "Unknown hold_token"}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if hold.Status != models.HoldStatusActive {
+		return nil, &HoldUnavailableError{Status: hold.Status}
+	}
+	if hold.InventoryType != inventoryType {
+		return nil, &BookingValidationError{Message: "hold_token does not hold this booking's inventory"}
+	}
+
+	if hold.ExpiresAt.Before(time.Now()) {
+		if err := releaseHoldInventory(tx, &hold); err != nil {
+			return nil, err
+		}
+		return nil, tx.Model(&hold).Update("status", models.HoldStatusExpired).Error
+	}
+	return &hold, nil
+}
+
+// takeStayHold hands the nights held under a hotel booking's hold token back so the booking can
+// reserve them in the same transaction. The hold must cover exactly the booking's room or room
+// category and stay.
+func takeStayHold(tx *gorm.DB, booking *models.HotelBooking) (*models.InventoryHold, error) {
+	hold, err := takeHold(tx, booking.HoldToken, booking.InventoryType)
+	if err != nil || hold == nil {
+		return nil, err
+	}
+
+	matches := *hold.HotelID == booking.HotelID &&
+		hold.CheckInDate.Format("2006-01-02") == booking.CheckInDate.Format("2006-01-02") &&
+		hold.CheckOutDate.Format("2006-01-02") == booking.CheckOutDate.Format("2006-01-02")
+	if booking.InventoryType == models.InventoryTypeRoom {
+		matches = matches && *hold.RoomID == *booking.RoomID
+	} else {
+		matches = matches && *hold.RoomCategoryID == *booking.RoomCategoryID
+	}
+	if !matches {
+		return nil, &BookingValidationError{Message: "hold_token does not hold this room and stay"}
+	}
+
+	if err := releaseHoldInventory(tx, hold); err != nil {
+		return nil, err
+	}
+	return hold, tx.Model(hold).Update("status", models.HoldStatusConsumed).Error
+}
+
+// takePackageHold marks the seats held under token as taken by a package booking, so they are not
+// counted twice when the booking's seats are checked
+func takePackageHold(tx *gorm.DB, token string, booking *models.PackageBooking) (*models.InventoryHold, error) {
+	hold, err := takeHold(tx, token, models.InventoryTypePackage)
+	if err != nil || hold == nil {
+		return nil, err
+	}
+
+	if *hold.PackageID != booking.PackageID || hold.TravelDate.Format("2006-01-02") != booking.TravelDate.Format("2006-01-02") {
+		return nil, &BookingValidationError{Message: "hold_token does not hold this package and travel date"}
+	}
+	if booking.NumPassengers > hold.Seats {
+		return nil, &BookingValidationError{
+			Message: fmt.Sprintf("hold_token holds %d seat(s) for %d passenger(s)", hold.Seats, booking.NumPassengers),
+		}
+	}
+	return hold, tx.Model(hold).Update("status", models.HoldStatusConsumed).Error
+}
+
+// newHoldToken returns a random token that is hard to guess
+func newHoldToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
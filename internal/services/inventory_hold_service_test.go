@@ -0,0 +1,340 @@
+package services
+
+import (
+	"errors"
+	"flyola-services/internal/models"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// holdContenders is how many holds race for the last unit of inventory
+const holdContenders = 8
+
+// raceHolds sends the same hold request from holdContenders goroutines at once and returns the
+// holds that were granted and the errors of the others
+func raceHolds(t *testing.T, service *HoldService, request HoldRequest) ([]*models.InventoryHold, []error) {
+	t.Helper()
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		holds []*models.InventoryHold
+		errs  []error
+	)
+	start := make(chan struct{})
+	for i := 0; i < holdContenders; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := request
+			<-start
+			hold, err := service.CreateHold(&req)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			holds = append(holds, hold)
+		}()
+	}
+	close(start)
+	wg.Wait()
+	return holds, errs
+}
+
+func TestCreateHoldLastRoomNight(t *testing.T) {
+	db := testDB(t)
+	fixture := createTestHotel(t, db)
+	service := NewHoldService(db, 15*time.Minute)
+
+	checkIn := daysFromToday(12)
+	holds, errs := raceHolds(t, service, HoldRequest{
+		RoomID:       &fixture.Room.ID,
+		CheckInDate:  checkIn,
+		CheckOutDate: checkIn.AddDate(0, 0, 1),
+	})
+
+	if len(holds) != 1 {
+		t.Fatalf("%d holds granted for the last room night, want 1 (errors: %v)", len(holds), errs)
+	}
+	for _, err := range errs {
+		var conflictErr *BookingConflictError
+		if !errors.As(err, &conflictErr) {
+			t.Errorf("losing hold failed with %v, want a booking conflict", err)
+		}
+	}
+
+	row := roomNights(t, db, fixture.Room.ID, checkIn, checkIn.AddDate(0, 0, 1))[checkIn.Format("2006-01-02")]
+	if row.IsAvailable || row.BlockSource != models.BlockSourceBooking {
+		t.Errorf("night = %+v, want held", row)
+	}
+}
+
+func TestCreateHoldLastAllotmentUnit(t *testing.T) {
+	db := testDB(t)
+	fixture := createTestHotel(t, db)
+	service := NewHoldService(db, 15*time.Minute)
+
+	checkIn := daysFromToday(20)
+	allotments := []models.RoomAllotment{
+		{HotelID: fixture.Hotel.ID, RoomCategoryID: fixture.Category.ID, Date: checkIn, Allotment: 1},
+		{HotelID: fixture.Hotel.ID, RoomCategoryID: fixture.Category.ID, Date: checkIn.AddDate(0, 0, 1), Allotment: 3, Sold: 1},
+	}
+	if err := db.Create(&allotments).Error; err != nil {
+		t.Fatalf("create allotments: %v", err)
+	}
+
+	holds, errs := raceHolds(t, service, HoldRequest{
+		HotelID:        &fixture.Hotel.ID,
+		RoomCategoryID: &fixture.Category.ID,
+		CheckInDate:    checkIn,
+		CheckOutDate:   checkIn.AddDate(0, 0, 2),
+	})
+
+	if len(holds) != 1 {
+		t.Fatalf("%d holds granted for the last allotment unit, want 1 (errors: %v)", len(holds), errs)
+	}
+	for _, err := range errs {
+		var allotmentErr *AllotmentUnavailableError
+		if !errors.As(err, &allotmentErr) {
+			t.Errorf("losing hold failed with %v, want the allotment to be unavailable", err)
+		}
+	}
+
+	// Only the winning hold took a unit of each night
+	for i, want := range []int{1, 2} {
+		var allotment models.RoomAllotment
+		if err := db.First(&allotment, allotments[i].ID).Error; err != nil {
+			t.Fatalf("reload allotment: %v", err)
+		}
+		if allotment.Sold != want {
+			t.Errorf("night %d sold = %d, want %d", i, allotment.Sold, want)
+		}
+	}
+}
+
+func TestCreateHoldLastPackageSeat(t *testing.T) {
+	db := testDB(t)
+	service := NewHoldService(db, 15*time.Minute)
+
+	pkg := models.HolidayPackage{
+		Title:             fmt.Sprintf("Test Package %d", testSequence.Add(1)),
+		DepartureCapacity: 3,
+		Status:            1,
+	}
+	if err := db.Omit(clause.Associations).Create(&pkg).Error; err != nil {
+		t.Fatalf("create package: %v", err)
+	}
+
+	// Two of the departure's three seats are already booked
+	travelDate := daysFromToday(30)
+	sequence := testSequence.Add(1)
+	booking := models.PackageBooking{
+		PackageID:        pkg.ID,
+		BookingReference: fmt.Sprintf("TPK%d", sequence),
+		PNR:              fmt.Sprintf("T%d", sequence),
+		GuestName:        "Test Guest",
+		GuestEmail:       "guest@example.com",
+		GuestPhone:       "9999999999",
+		NumPassengers:    2,
+		TravelDate:       travelDate,
+		TotalAmount:      10000,
+		BookingStatus:    "confirmed",
+		PaymentStatus:    "paid",
+	}
+	if err := db.Omit(clause.Associations).Create(&booking).Error; err != nil {
+		t.Fatalf("create package booking: %v", err)
+	}
+
+	holds, errs := raceHolds(t, service, HoldRequest{
+		PackageID:  &pkg.ID,
+		TravelDate: travelDate.Format("2006-01-02"),
+		Seats:      1,
+	})
+
+	if len(holds) != 1 {
+		t.Fatalf("%d holds granted for the last package seat, want 1 (errors: %v)", len(holds), errs)
+	}
+	for _, err := range errs {
+		var seatsErr *PackageSeatsError
+		if !errors.As(err, &seatsErr) || seatsErr.Available != 0 {
+			t.Errorf("losing hold failed with %v, want no seats left", err)
+		}
+	}
+}
+
+func TestExpireHoldsReleasesInventory(t *testing.T) {
+	db := testDB(t)
+	fixture := createTestHotel(t, db)
+	service := NewHoldService(db, 15*time.Minute)
+
+	checkIn, checkOut := daysFromToday(40), daysFromToday(42)
+	hold, err := service.CreateHold(&HoldRequest{RoomID: &fixture.Room.ID, CheckInDate: checkIn, CheckOutDate: checkOut})
+	if err != nil {
+		t.Fatalf("create hold: %v", err)
+	}
+
+	// A live hold is left alone by the sweeper
+	fresh, err := service.CreateHold(&HoldRequest{
+		RoomID:       &fixture.Room.ID,
+		CheckInDate:  checkOut,
+		CheckOutDate: checkOut.AddDate(0, 0, 1),
+	})
+	if err != nil {
+		t.Fatalf("create second hold: %v", err)
+	}
+
+	if err := db.Model(hold).Update("expires_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatalf("age hold: %v", err)
+	}
+	// Other tests share the table, so sweep until nothing is left rather than counting
+	for {
+		expired, err := service.ExpireHolds(100)
+		if err != nil {
+			t.Fatalf("expire holds: %v", err)
+		}
+		if expired == 0 {
+			break
+		}
+	}
+
+	stored, err := service.GetHold(hold.Token)
+	if err != nil {
+		t.Fatalf("reload hold: %v", err)
+	}
+	if stored.Status != models.HoldStatusExpired {
+		t.Errorf("expired hold status = %q, want %q", stored.Status, models.HoldStatusExpired)
+	}
+	for key, row := range roomNights(t, db, fixture.Room.ID, checkIn, checkOut) {
+		if !row.IsAvailable || row.BlockSource != "" {
+			t.Errorf("night %s = %+v, want reopened", key, row)
+		}
+	}
+
+	stored, err = service.GetHold(fresh.Token)
+	if err != nil {
+		t.Fatalf("reload second hold: %v", err)
+	}
+	if stored.Status != models.HoldStatusActive {
+		t.Errorf("live hold status = %q, want %q", stored.Status, models.HoldStatusActive)
+	}
+	if row := roomNights(t, db, fixture.Room.ID, checkOut, checkOut.AddDate(0, 0, 1))[checkOut.Format("2006-01-02")]; row.IsAvailable {
+		t.Errorf("night of the live hold was reopened")
+	}
+
+	// The expired hold can no longer be booked against
+	var unavailableErr *HoldUnavailableError
+	if err := service.ReleaseHold(hold.Token); !errors.As(err, &unavailableErr) {
+		t.Errorf("releasing an expired hold returned %v, want it to be unavailable", err)
+	}
+}
+
+func TestBulkReopenKeepsHeldNights(t *testing.T) {
+	db := testDB(t)
+	fixture := createTestHotel(t, db)
+	service := NewHoldService(db, 15*time.Minute)
+
+	checkIn, checkOut := daysFromToday(45), daysFromToday(47)
+	if _, err := service.CreateHold(&HoldRequest{RoomID: &fixture.Room.ID, CheckInDate: checkIn, CheckOutDate: checkOut}); err != nil {
+		t.Fatalf("create hold: %v", err)
+	}
+
+	// Reopen the held nights and the night after them
+	reopen := true
+	result, err := NewRoomAvailabilityService(db).BulkUpdateAvailability(&BulkAvailabilityUpdate{
+		RoomIDs:     []uint{fixture.Room.ID},
+		StartDate:   checkIn,
+		EndDate:     checkOut,
+		IsAvailable: &reopen,
+	})
+	if err != nil {
+		t.Fatalf("bulk reopen: %v", err)
+	}
+	if result.Skipped != 2 {
+		t.Errorf("skipped = %d, want the 2 held nights", result.Skipped)
+	}
+
+	nights := roomNights(t, db, fixture.Room.ID, checkIn, checkOut.AddDate(0, 0, 1))
+	for _, night := range stayNights(checkIn, checkOut) {
+		if row := nights[night.Format("2006-01-02")]; row.IsAvailable || row.BlockSource != models.BlockSourceBooking {
+			t.Errorf("held night %s is available=%v source=%q, want still held", night.Format("2006-01-02"), row.IsAvailable, row.BlockSource)
+		}
+	}
+	if row := nights[checkOut.Format("2006-01-02")]; !row.IsAvailable {
+		t.Errorf("night after the hold is available=%v, want open", row.IsAvailable)
+	}
+}
+
+func TestCreateBookingConsumesHold(t *testing.T) {
+	db := testDB(t)
+	fixture := createTestHotel(t, db)
+	holds := NewHoldService(db, 15*time.Minute)
+	bookings := NewBookingService(db, 0)
+
+	checkIn, checkOut := daysFromToday(50), daysFromToday(52)
+	hold, err := holds.CreateHold(&HoldRequest{RoomID: &fixture.Room.ID, CheckInDate: checkIn, CheckOutDate: checkOut})
+	if err != nil {
+		t.Fatalf("create hold: %v", err)
+	}
+
+	// Without the hold token, nobody else can book the held nights
+	other := newTestStayBooking(fixture, checkIn, checkOut)
+	var conflictErr *BookingConflictError
+	if err := bookings.CreateBooking(other, "test"); !errors.As(err, &conflictErr) {
+		t.Fatalf("booking held nights without the token returned %v, want a booking conflict", err)
+	}
+
+	booking := newTestStayBooking(fixture, checkIn, checkOut)
+	booking.HoldToken = hold.Token
+	if err := bookings.CreateBooking(booking, "test"); err != nil {
+		t.Fatalf("create booking with hold: %v", err)
+	}
+
+	stored, err := holds.GetHold(hold.Token)
+	if err != nil {
+		t.Fatalf("reload hold: %v", err)
+	}
+	if stored.Status != models.HoldStatusConsumed || stored.BookingID == nil || *stored.BookingID != booking.ID {
+		t.Errorf("hold = status %q booking %v, want consumed by booking %d", stored.Status, stored.BookingID, booking.ID)
+	}
+	for key, row := range roomNights(t, db, fixture.Room.ID, checkIn, checkOut) {
+		if row.IsAvailable || row.BlockSource != models.BlockSourceBooking {
+			t.Errorf("night %s = %+v, want reserved by the booking", key, row)
+		}
+	}
+
+	// A consumed hold is neither swept nor usable again
+	if _, err := holds.ExpireHolds(100); err != nil {
+		t.Fatalf("expire holds: %v", err)
+	}
+	for key, row := range roomNights(t, db, fixture.Room.ID, checkIn, checkOut) {
+		if row.IsAvailable {
+			t.Errorf("night %s of the booking was reopened by the sweeper", key)
+		}
+	}
+	again := newTestStayBooking(fixture, checkIn, checkOut)
+	again.HoldToken = hold.Token
+	var unavailableErr *HoldUnavailableError
+	if err := bookings.CreateBooking(again, "test"); !errors.As(err, &unavailableErr) {
+		t.Errorf("reusing a consumed hold returned %v, want it to be unavailable", err)
+	}
+}
+
+// newTestStayBooking returns an unsaved booking of the test hotel's room for one guest
+func newTestStayBooking(fixture *testHotel, checkIn, checkOut time.Time) *models.HotelBooking {
+	roomID := fixture.Room.ID
+	return &models.HotelBooking{
+		HotelID:        fixture.Hotel.ID,
+		RoomID:         &roomID,
+		GuestName:      "Test Guest",
+		GuestEmail:     "guest@example.com",
+		GuestPhone:     "9999999999",
+		CheckInDate:    checkIn,
+		CheckOutDate:   checkOut,
+		NumberOfGuests: 1,
+	}
+}
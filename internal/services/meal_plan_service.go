@@ -1,11 +1,21 @@
 package services
 
 import (
+	"errors"
 	"flyola-services/internal/models"
+	"fmt"
+	"time"
 
 	"gorm.io/gorm"
 )
 
+// MealPlanOffer is a meal plan that can be booked with a room category for a stay
+type MealPlanOffer struct {
+	MealPlan       models.MealPlan `json:"mealPlan"`
+	Nights         []NightlyRate   `json:"nights"`
+	PricePerPerson float64         `json:"pricePerPerson"` // For the whole stay
+}
+
 type MealPlanService struct {
 	db *gorm.DB
 }
@@ -60,4 +70,187 @@ func (s *MealPlanService) GetActiveMealPlans() ([]models.MealPlan, error) {
 	var mealPlans []models.MealPlan
 	err := s.db.Where("status = ? AND is_active = ?", 0, true).Find(&mealPlans).Error
 	return mealPlans, err
-}
\ No newline at end of file
+}
+
+func (s *MealPlanService) GetMealPlanRates(roomCategoryID uint) ([]models.MealPlanRate, error) {
+	var rates []models.MealPlanRate
+	err := s.db.Where("room_category_id = ?", roomCategoryID).Order("meal_plan_id, valid_from").Find(&rates).Error
+	return rates, err
+}
+
+func (s *MealPlanService) CreateMealPlanRate(rate *models.MealPlanRate) error {
+	if err := s.validateMealPlanRate(rate); err != nil {
+		return err
+	}
+	return s.db.Create(rate).Error
+}
+
+func (s *MealPlanService) UpdateMealPlanRate(id uint, updates *models.MealPlanRate) (*models.MealPlanRate, error) {
+	var rate models.MealPlanRate
+	if err := s.db.First(&rate, id).Error; err != nil {
+		return nil, err
+	}
+
+	merged := rate
+	if updates.MealPlanID != 0 {
+		merged.MealPlanID = updates.MealPlanID
+	}
+	if updates.PricePerPerson != 0 {
+		merged.PricePerPerson = updates.PricePerPerson
+	}
+	merged.ValidFrom = updates.ValidFrom
+	merged.ValidTo = updates.ValidTo
+	merged.Status = updates.Status
+	if err := s.validateMealPlanRate(&merged); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(&rate).Select("meal_plan_id", "price_per_person", "valid_from", "valid_to", "status").
+		Updates(&merged).Error; err != nil {
+		return nil, err
+	}
+	return &merged, nil
+}
+
+func (s *MealPlanService) DeleteMealPlanRate(id uint) error {
+	return s.db.Delete(&models.MealPlanRate{}, id).Error
+}
+
+func (s *MealPlanService) validateMealPlanRate(rate *models.MealPlanRate) error {
+	if rate.PricePerPerson < 0 {
+		return &BookingValidationError{Message: "pricePerPerson cannot be negative"}
+	}
+	if rate.ValidFrom != nil && rate.ValidTo != nil && rate.ValidTo.Before(*rate.ValidFrom) {
+		return &BookingValidationError{Message: "validTo must not be before validFrom"}
+	}
+	if err := s.db.Select("id").First(&models.RoomCategory{}, rate.RoomCategoryID).Error; err != nil {
+		return err
+	}
+	return s.db.Select("id").First(&models.MealPlan{}, rate.MealPlanID).Error
+}
+
+// GetMealPlanOffers returns the active meal plans that have a rate on every night of the stay for
+// a room category, with their per person price
+func (s *MealPlanService) GetMealPlanOffers(roomCategoryID uint, checkIn, checkOut time.Time) ([]MealPlanOffer, error) {
+	offers, err := mealPlanOffers(s.db, []uint{roomCategoryID}, dateOnly(checkIn), dateOnly(checkOut))
+	if err != nil {
+		return nil, err
+	}
+	return offers[roomCategoryID], nil
+}
+
+// mealPlanOffers prices every active meal plan for the stay for each of the given room categories
+// in two queries. Plans missing a rate on any night of the stay are left out.
+func mealPlanOffers(tx *gorm.DB, roomCategoryIDs []uint, checkIn, checkOut time.Time) (map[uint][]MealPlanOffer, error) {
+	rates, err := loadMealPlanRates(tx, roomCategoryIDs, checkIn, checkOut)
+	if err != nil {
+		return nil, err
+	}
+
+	var plans []models.MealPlan
+	if err := tx.Where("status = ?", 0).Order("id").Find(&plans).Error; err != nil {
+		return nil, err
+	}
+
+	nights := stayNights(checkIn, checkOut)
+	offers := make(map[uint][]MealPlanOffer)
+	for _, categoryID := range roomCategoryIDs {
+		for _, plan := range plans {
+			nightly, missing := nightlyMealPlanRates(rates, categoryID, plan.ID, nights)
+			if missing != "" {
+				continue
+			}
+			offer := MealPlanOffer{MealPlan: plan, Nights: nightly}
+			for _, night := range nightly {
+				offer.PricePerPerson += night.Price
+			}
+			offer.PricePerPerson = roundAmount(offer.PricePerPerson)
+			offers[categoryID] = append(offers[categoryID], offer)
+		}
+	}
+	return offers, nil
+}
+
+// loadMealPlanRates loads the active meal plan rates of the given room categories that apply to
+// at least one night of the stay
+func loadMealPlanRates(tx *gorm.DB, roomCategoryIDs []uint, checkIn, checkOut time.Time) ([]models.MealPlanRate, error) {
+	var rates []models.MealPlanRate
+	err := tx.Where("room_category_id IN ? AND status = ?", roomCategoryIDs, 0).
+		Where("valid_from IS NULL OR valid_from < ?", checkOut).
+		Where("valid_to IS NULL OR valid_to >= ?", checkIn).
+		Order("id").Find(&rates).Error
+	return rates, err
+}
+
+// nightlyMealPlanRates picks the per person rate of a meal plan for each night. A rate with a date
+// range wins over an open-ended one, and the most recently added rate wins among equals. If a night
+// has no rate, its date is returned as missing.
+func nightlyMealPlanRates(rates []models.MealPlanRate, roomCategoryID, mealPlanID uint, nights []time.Time) ([]NightlyRate, string) {
+	nightly := make([]NightlyRate, 0, len(nights))
+	for _, night := range nights {
+		var chosen *models.MealPlanRate
+		for i := range rates {
+			rate := &rates[i]
+			if rate.RoomCategoryID != roomCategoryID || rate.MealPlanID != mealPlanID || !mealPlanRateCovers(rate, night) {
+				continue
+			}
+			if chosen == nil || isDatedMealPlanRate(rate) || !isDatedMealPlanRate(chosen) {
+				chosen = rate
+			}
+		}
+		if chosen == nil {
+			return nil, night.Format("2006-01-02")
+		}
+		nightly = append(nightly, NightlyRate{Date: night.Format("2006-01-02"), Price: chosen.PricePerPerson})
+	}
+	return nightly, ""
+}
+
+func isDatedMealPlanRate(rate *models.MealPlanRate) bool {
+	return rate.ValidFrom != nil || rate.ValidTo != nil
+}
+
+func mealPlanRateCovers(rate *models.MealPlanRate, night time.Time) bool {
+	if rate.ValidFrom != nil && night.Before(dateOnly(*rate.ValidFrom)) {
+		return false
+	}
+	if rate.ValidTo != nil && night.After(dateOnly(*rate.ValidTo)) {
+		return false
+	}
+	return true
+}
+
+// priceMealPlan prices a meal plan for every guest on every night of a stay
+func priceMealPlan(tx *gorm.DB, mealPlanID uint, category *models.RoomCategory, persons int, nights []time.Time) (*MealPlanCharge, error) {
+	var plan models.MealPlan
+	if err := tx.Where("status = ?", 0).First(&plan, mealPlanID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &BookingValidationError{Message: "Meal plan not found"}
+		}
+		return nil, err
+	}
+
+	rates, err := loadMealPlanRates(tx, []uint{category.ID}, nights[0], nights[len(nights)-1].AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	nightly, missing := nightlyMealPlanRates(rates, category.ID, plan.ID, nights)
+	if missing != "" {
+		return nil, &BookingValidationError{
+			Message: fmt.Sprintf("%s is not offered for %s rooms on %s", plan.Name, category.Name, missing),
+		}
+	}
+
+	charge := &MealPlanCharge{
+		MealPlanID: plan.ID,
+		Code:       plan.Code,
+		Name:       plan.Name,
+		Persons:    persons,
+		Nights:     nightly,
+	}
+	for _, night := range nightly {
+		charge.Total += night.Price * float64(persons)
+	}
+	charge.Total = roundAmount(charge.Total)
+	return charge, nil
+}
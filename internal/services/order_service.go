@@ -0,0 +1,342 @@
+package services
+
+import (
+	"errors"
+	"flyola-services/internal/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxRoomsPerOrder bounds how many rooms a single checkout may book
+const maxRoomsPerOrder = 10
+
+// ErrPaymentOrderMismatch is returned when a verified Razorpay order was not issued for the booking order
+var ErrPaymentOrderMismatch = errors.New("payment order does not belong to this booking order")
+
+// ErrBookingPaymentMismatch is returned when a verified Razorpay order was not issued for the booking
+// or for its current amount
+var ErrBookingPaymentMismatch = errors.New("payment order was not issued for this booking and its amount")
+
+// OrderCancellation is the refund for cancelling the remaining rooms of an order
+type OrderCancellation struct {
+	Bookings     []BookingCancellation `json:"bookings"`
+	RefundAmount float64               `json:"refund_amount"`
+}
+
+// BookingCancellation is the refund for cancelling one room of an order
+type BookingCancellation struct {
+	BookingID        uint               `json:"booking_id"`
+	BookingReference string             `json:"booking_reference"`
+	Cancellation     *CancellationQuote `json:"cancellation"`
+}
+
+type OrderService struct {
+	db             *gorm.DB
+	bookingService *BookingService
+}
+
+func NewOrderService(db *gorm.DB, bookingService *BookingService) *OrderService {
+	return &OrderService{db: db, bookingService: bookingService}
+}
+
+func (s *OrderService) GetOrderByID(id uint) (*models.BookingOrder, error) {
+	var order models.BookingOrder
+	err := s.db.Preload("Bookings", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Preload("Bookings.Hotel").Preload("Bookings.Room").First(&order, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// CreateOrder books every room of the order in one transaction: if any room cannot be priced or
+// reserved, none of them are booked. The order totals are the sum of its bookings.
+func (s *OrderService) CreateOrder(order *models.BookingOrder, actor string) error {
+	bookings := order.Bookings
+	if len(bookings) == 0 {
+		return &BookingValidationError{Message: "An order needs at least one room"}
+	}
+	if len(bookings) > maxRoomsPerOrder {
+		return &BookingValidationError{Message: fmt.Sprintf("An order can book at most %d rooms", maxRoomsPerOrder)}
+	}
+
+	order.Bookings = nil
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		order.ID = 0
+		order.TotalAmount, order.TaxAmount, order.FinalAmount = 0, 0, 0
+		order.PaymentStatus = models.PaymentStatusPending
+		order.RazorpayOrderID, order.PaymentID, order.PaymentMethod = "", "", ""
+		order.RazorpayOrderAmount = 0
+		if err := createOrderWithReference(tx, order); err != nil {
+			return err
+		}
+
+		for i := range bookings {
+			booking := &bookings[i]
+			booking.ID = 0
+			booking.OrderID = &order.ID
+			booking.UserID = order.UserID
+			if booking.GuestName == "" {
+				booking.GuestName = order.GuestName
+			}
+			if booking.GuestEmail == "" {
+				booking.GuestEmail = order.GuestEmail
+			}
+			if booking.GuestPhone == "" {
+				booking.GuestPhone = order.GuestPhone
+			}
+
+			if err := s.bookingService.createBooking(tx, booking, actor); err != nil {
+				return err
+			}
+
+			order.TotalAmount += booking.TotalAmount
+			order.TaxAmount += booking.TaxAmount
+			order.FinalAmount += booking.FinalAmount
+		}
+
+		order.TotalAmount = roundAmount(order.TotalAmount)
+		order.TaxAmount = roundAmount(order.TaxAmount)
+		order.FinalAmount = roundAmount(order.FinalAmount)
+		return tx.Model(&models.BookingOrder{}).Where("id = ?", order.ID).Updates(map[string]interface{}{
+			"total_amount": order.TotalAmount,
+			"tax_amount":   order.TaxAmount,
+			"final_amount": order.FinalAmount,
+		}).Error
+	})
+	order.Bookings = bookings
+	return err
+}
+
+// createOrderWithReference inserts an order under a freshly generated reference, retrying on collision
+func createOrderWithReference(tx *gorm.DB, order *models.BookingOrder) error {
+	for attempt := 1; ; attempt++ {
+		order.OrderReference = ""
+		err := tx.Omit(clause.Associations).Create(order).Error
+		if err == nil || !errors.Is(err, gorm.ErrDuplicatedKey) || attempt == maxReferenceAttempts {
+			return err
+		}
+	}
+}
+
+// PayableAmount returns the amount in paise to create the Razorpay order of an unpaid order for
+func (s *OrderService) PayableAmount(order *models.BookingOrder) (int64, error) {
+	if err := checkOrderPayable(order); err != nil {
+		return 0, err
+	}
+	return amountInPaise(order.FinalAmount), nil
+}
+
+// AttachPaymentOrder stores the Razorpay order the guest will pay an unpaid order with, and the
+// amount in paise it was created for. The order is locked and checked again, so a room cancelled
+// or a payment confirmed while the Razorpay order was being created is not missed.
+func (s *OrderService) AttachPaymentOrder(id uint, razorpayOrderID string, amount int64) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var order models.BookingOrder
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, id).Error; err != nil {
+			return err
+		}
+		if err := checkOrderPayable(&order); err != nil {
+			return err
+		}
+		if amount != amountInPaise(order.FinalAmount) {
+			return &BookingValidationError{Message: "Order total changed while the payment order was created, create it again"}
+		}
+
+		return tx.Model(&models.BookingOrder{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"razorpay_order_id":     razorpayOrderID,
+			"razorpay_order_amount": amount,
+		}).Error
+	})
+}
+
+// checkOrderPayable tells whether an order still has rooms waiting to be paid for
+func checkOrderPayable(order *models.BookingOrder) error {
+	if order.PaymentStatus != models.PaymentStatusPending {
+		return &BookingValidationError{Message: "Order is already paid"}
+	}
+	if order.FinalAmount <= 0 {
+		return &BookingValidationError{Message: "Order has no rooms left to pay for"}
+	}
+	return nil
+}
+
+// ConfirmPayment marks an order as paid and confirms its rooms that are still waiting for payment;
+// rooms cancelled or expired in the meantime are left alone. Either every pending room is confirmed
+// or none is; verifying the same payment twice leaves the order unchanged.
+func (s *OrderService) ConfirmPayment(id uint, razorpayOrderID, paymentID, paymentMethod string) (*models.BookingOrder, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var order models.BookingOrder
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, id).Error; err != nil {
+			return err
+		}
+
+		if order.RazorpayOrderID == "" || order.RazorpayOrderID != razorpayOrderID {
+			return ErrPaymentOrderMismatch
+		}
+		if order.PaymentStatus == models.PaymentStatusPaid && order.PaymentID == paymentID {
+			return nil
+		}
+		// Cancelling or changing a room after the Razorpay order was created changes the total
+		if order.RazorpayOrderAmount != amountInPaise(order.FinalAmount) {
+			return ErrPaymentOrderMismatch
+		}
+
+		bookings, err := lockOrderBookings(tx, order.ID)
+		if err != nil {
+			return err
+		}
+		confirmed := 0
+		for i := range bookings {
+			if bookings[i].BookingStatus != models.BookingStatusPending {
+				continue
+			}
+			if err := confirmBookingPayment(tx, &bookings[i], paymentID, paymentMethod); err != nil {
+				return err
+			}
+			confirmed++
+		}
+		if confirmed == 0 {
+			return &BookingValidationError{Message: "Order has no rooms left to confirm"}
+		}
+
+		return tx.Model(&models.BookingOrder{}).Where("id = ?", order.ID).Updates(map[string]interface{}{
+			"payment_status": models.PaymentStatusPaid,
+			"payment_id":     paymentID,
+			"payment_method": paymentMethod,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetOrderByID(id)
+}
+
+// PreviewCancellation returns the refund for cancelling every room of the order that can still be cancelled
+func (s *OrderService) PreviewCancellation(id uint) (*OrderCancellation, error) {
+	if err := s.db.Select("id").First(&models.BookingOrder{}, id).Error; err != nil {
+		return nil, err
+	}
+
+	var bookings []models.HotelBooking
+	if err := s.db.Where("order_id = ?", id).Order("id").Find(&bookings).Error; err != nil {
+		return nil, err
+	}
+
+	result := &OrderCancellation{}
+	for i := range bookings {
+		if !models.CanTransitionBookingStatus(bookings[i].BookingStatus, models.BookingStatusCancelled) {
+			continue
+		}
+		quote, err := quoteBookingCancellation(s.db, &bookings[i], time.Now())
+		if err != nil {
+			return nil, err
+		}
+		result.add(&bookings[i], quote)
+	}
+	if len(result.Bookings) == 0 {
+		return nil, &BookingValidationError{Message: "Order has no rooms left to cancel"}
+	}
+	return result, nil
+}
+
+// CancelOrder cancels every room of the order that can still be cancelled, refunding each under
+// the cancellation rules it was booked with. Single rooms are cancelled through their booking.
+func (s *OrderService) CancelOrder(id uint, actor, reason string) (*OrderCancellation, error) {
+	if reason == "" {
+		reason = "Order cancelled"
+	}
+
+	result := &OrderCancellation{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.BookingOrder{}, id).Error; err != nil {
+			return err
+		}
+
+		bookings, err := lockOrderBookings(tx, id)
+		if err != nil {
+			return err
+		}
+		for i := range bookings {
+			if !models.CanTransitionBookingStatus(bookings[i].BookingStatus, models.BookingStatusCancelled) {
+				continue
+			}
+			quote, err := cancelBooking(tx, &bookings[i], actor, reason)
+			if err != nil {
+				return err
+			}
+			result.add(&bookings[i], quote)
+		}
+		if len(result.Bookings) == 0 {
+			return &BookingValidationError{Message: "Order has no rooms left to cancel"}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (c *OrderCancellation) add(booking *models.HotelBooking, quote *CancellationQuote) {
+	c.Bookings = append(c.Bookings, BookingCancellation{
+		BookingID:        booking.ID,
+		BookingReference: booking.BookingReference,
+		Cancellation:     quote,
+	})
+	c.RefundAmount = roundAmount(c.RefundAmount + quote.RefundAmount)
+}
+
+// lockBookingOrder locks the order of a booking, if it has one, before the booking itself is locked.
+// Orders are always locked before their bookings, so single-room changes cannot deadlock with
+// a payment confirming the whole order.
+func lockBookingOrder(tx *gorm.DB, bookingID uint) error {
+	var booking models.HotelBooking
+	if err := tx.Unscoped().Select("id", "order_id").First(&booking, bookingID).Error; err != nil {
+		return err
+	}
+	if booking.OrderID == nil {
+		return nil
+	}
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.BookingOrder{}, *booking.OrderID).Error
+}
+
+// syncOrderTotals makes the totals of an unpaid order the sum of the rooms still to be paid for,
+// after one of them was cancelled, modified, deleted or restored. Paid orders keep what was paid.
+func syncOrderTotals(tx *gorm.DB, orderID *uint) error {
+	if orderID == nil {
+		return nil
+	}
+
+	var totals struct {
+		TotalAmount float64
+		TaxAmount   float64
+		FinalAmount float64
+	}
+	if err := tx.Model(&models.HotelBooking{}).
+		Select("COALESCE(SUM(total_amount), 0) AS total_amount, COALESCE(SUM(tax_amount), 0) AS tax_amount, COALESCE(SUM(final_amount), 0) AS final_amount").
+		Where("order_id = ? AND booking_status NOT IN ?", *orderID, []string{models.BookingStatusCancelled, models.BookingStatusExpired}).
+		Scan(&totals).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.BookingOrder{}).
+		Where("id = ? AND payment_status = ?", *orderID, models.PaymentStatusPending).
+		Updates(map[string]interface{}{
+			"total_amount": roundAmount(totals.TotalAmount),
+			"tax_amount":   roundAmount(totals.TaxAmount),
+			"final_amount": roundAmount(totals.FinalAmount),
+		}).Error
+}
+
+func lockOrderBookings(tx *gorm.DB, orderID uint) ([]models.HotelBooking, error) {
+	var bookings []models.HotelBooking
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id = ?", orderID).Order("id").Find(&bookings).Error
+	return bookings, err
+}
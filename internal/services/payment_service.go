@@ -121,6 +121,30 @@ func (s *PaymentService) UpdatePaymentStatus(id uint, status string) (*models.Ho
 	return &payment, nil
 }
 
+// DeletePayment soft deletes a payment; RestorePayment brings it back
+func (s *PaymentService) DeletePayment(id uint) error {
+	var payment models.HotelPayment
+	if err := s.db.Select("id").First(&payment, id).Error; err != nil {
+		return err
+	}
+	return s.db.Delete(&payment).Error
+}
+
+// RestorePayment brings back a deleted payment
+func (s *PaymentService) RestorePayment(id uint) (*models.HotelPayment, error) {
+	var payment models.HotelPayment
+	if err := s.db.Unscoped().First(&payment, id).Error; err != nil {
+		return nil, err
+	}
+	if !payment.DeletedAt.Valid {
+		return nil, &BookingValidationError{Message: "Payment is not deleted"}
+	}
+	if err := s.db.Unscoped().Model(&payment).Update("deleted_at", nil).Error; err != nil {
+		return nil, err
+	}
+	return s.GetPaymentByID(id)
+}
+
 func (s *PaymentService) GetPaymentsByStatus(status string) ([]models.HotelPayment, error) {
 	var payments []models.HotelPayment
 	err := s.db.Preload("Booking").Where("status = ?", status).Find(&payments).Error
@@ -0,0 +1,307 @@
+package services
+
+import (
+	"errors"
+	"flyola-services/internal/models"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// maxRatePreviewNights caps the date range of a room rate preview
+const maxRatePreviewNights = 366
+
+// Where the price of a night came from
+const (
+	RateSourceRoom     = "room"      // The room's occupancy or base price
+	RateSourceRatePlan = "rate_plan" // A rate plan rule adjusted the room's price
+	RateSourceOverride = "override"  // A manual RoomAvailability price
+)
+
+type RatePlanService struct {
+	db *gorm.DB
+}
+
+func NewRatePlanService(db *gorm.DB) *RatePlanService {
+	return &RatePlanService{db: db}
+}
+
+func (s *RatePlanService) GetHotelRatePlans(hotelID uint) ([]models.RatePlan, error) {
+	var plans []models.RatePlan
+	err := s.db.Preload("Rules", func(db *gorm.DB) *gorm.DB {
+		return db.Order("priority DESC, id")
+	}).Where("hotel_id = ?", hotelID).Order("id").Find(&plans).Error
+	return plans, err
+}
+
+func (s *RatePlanService) GetRatePlan(id uint) (*models.RatePlan, error) {
+	var plan models.RatePlan
+	if err := s.db.Preload("Rules", func(db *gorm.DB) *gorm.DB {
+		return db.Order("priority DESC, id")
+	}).First(&plan, id).Error; err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+func (s *RatePlanService) CreateRatePlan(plan *models.RatePlan) error {
+	if err := s.validateRatePlan(plan); err != nil {
+		return err
+	}
+	return s.db.Create(plan).Error
+}
+
+// UpdateRatePlan changes a rate plan. When updates carries rules they replace the plan's rules.
+func (s *RatePlanService) UpdateRatePlan(id uint, updates *models.RatePlan) (*models.RatePlan, error) {
+	var plan models.RatePlan
+	if err := s.db.First(&plan, id).Error; err != nil {
+		return nil, err
+	}
+
+	merged := plan
+	if updates.Name != "" {
+		merged.Name = updates.Name
+	}
+	if updates.Description != "" {
+		merged.Description = updates.Description
+	}
+	merged.RoomCategoryID = updates.RoomCategoryID
+	merged.Status = updates.Status
+	merged.Rules = updates.Rules
+	if err := s.validateRatePlan(&merged); err != nil {
+		return nil, err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&plan).Select("name", "description", "room_category_id", "status").
+			Updates(&merged).Error; err != nil {
+			return err
+		}
+		if updates.Rules == nil {
+			return nil
+		}
+		if err := tx.Where("rate_plan_id = ?", plan.ID).Delete(&models.RatePlanRule{}).Error; err != nil {
+			return err
+		}
+		for i := range merged.Rules {
+			merged.Rules[i].ID = 0
+			merged.Rules[i].RatePlanID = plan.ID
+		}
+		if len(merged.Rules) == 0 {
+			return nil
+		}
+		return tx.Create(&merged.Rules).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetRatePlan(plan.ID)
+}
+
+func (s *RatePlanService) DeleteRatePlan(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("rate_plan_id = ?", id).Delete(&models.RatePlanRule{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.RatePlan{}, id).Error
+	})
+}
+
+// GetRoomRates resolves the nightly price of a room for a number of guests on each night from
+// checkIn up to checkOut, the same way a booking would be priced
+func (s *RatePlanService) GetRoomRates(roomID uint, checkIn, checkOut time.Time, guests int) ([]NightlyRate, error) {
+	checkIn, checkOut = dateOnly(checkIn), dateOnly(checkOut)
+	if !checkOut.After(checkIn) {
+		return nil, &BookingValidationError{Message: "to must be after from"}
+	}
+	nights := stayNights(checkIn, checkOut)
+	if len(nights) > maxRatePreviewNights {
+		return nil, &BookingValidationError{Message: fmt.Sprintf("Rates can be shown for at most %d nights", maxRatePreviewNights)}
+	}
+	if guests <= 0 {
+		guests = 1
+	}
+
+	var room models.Room
+	if err := s.db.First(&room, roomID).Error; err != nil {
+		return nil, err
+	}
+
+	overrides, err := nightlyPriceOverrides(s.db, room.ID, checkIn, checkOut)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := loadRatePlanRules(s.db, []uint{room.HotelID}, checkIn, checkOut)
+	if err != nil {
+		return nil, err
+	}
+
+	rate := occupancyRate(&room, guests)
+	rates := make([]NightlyRate, 0, len(nights))
+	for _, night := range nights {
+		rates = append(rates, nightlyRoomRate(&room, night, rate, overrides, rules))
+	}
+	return rates, nil
+}
+
+func (s *RatePlanService) validateRatePlan(plan *models.RatePlan) error {
+	if plan.Name == "" {
+		return &BookingValidationError{Message: "name is required"}
+	}
+	if err := s.db.Select("id").First(&models.Hotel{}, plan.HotelID).Error; err != nil {
+		return err
+	}
+	if plan.RoomCategoryID != nil {
+		var category models.RoomCategory
+		if err := s.db.Select("id", "hotel_id").First(&category, *plan.RoomCategoryID).Error; err != nil {
+			return err
+		}
+		if category.HotelID != nil && *category.HotelID != plan.HotelID {
+			return &BookingValidationError{Message: "roomCategoryId belongs to another hotel"}
+		}
+	}
+
+	for i := range plan.Rules {
+		if err := validateRatePlanRule(&plan.Rules[i]); err != nil {
+			return &BookingValidationError{Message: fmt.Sprintf("rule %d: %s", i+1, err.Error())}
+		}
+	}
+	return nil
+}
+
+func validateRatePlanRule(rule *models.RatePlanRule) error {
+	switch rule.Adjustment {
+	case models.RateAdjustmentPercent:
+		if rule.Value <= -100 {
+			return errors.New("a percent adjustment must be greater than -100")
+		}
+	case models.RateAdjustmentAmount:
+	case models.RateAdjustmentFixed:
+		if rule.Value <= 0 {
+			return errors.New("a fixed price must be greater than 0")
+		}
+	default:
+		return fmt.Errorf("adjustment must be one of %s, %s or %s",
+			models.RateAdjustmentPercent, models.RateAdjustmentAmount, models.RateAdjustmentFixed)
+	}
+
+	if rule.ValidFrom != nil && rule.ValidTo != nil && rule.ValidTo.Before(*rule.ValidFrom) {
+		return errors.New("validTo must not be before validFrom")
+	}
+	for _, day := range rule.GetWeekdays() {
+		if day < 0 || day > 6 {
+			return errors.New("weekdays must be between 0 (Sunday) and 6 (Saturday)")
+		}
+	}
+	return nil
+}
+
+// ratePlanRules holds the active rate plan rules of some hotels, each hotel's rules ordered so the
+// first one covering a night is the one that applies
+type ratePlanRules struct {
+	byHotel map[uint][]scopedRateRule
+}
+
+type scopedRateRule struct {
+	roomCategoryID *uint
+	rule           models.RatePlanRule
+	weekdays       map[time.Weekday]bool
+}
+
+// loadRatePlanRules loads the rules of the hotels' active rate plans that cover at least one
+// night from checkIn up to checkOut, in two queries
+func loadRatePlanRules(tx *gorm.DB, hotelIDs []uint, checkIn, checkOut time.Time) (*ratePlanRules, error) {
+	var plans []models.RatePlan
+	if err := tx.Preload("Rules", func(db *gorm.DB) *gorm.DB {
+		return db.Where("valid_from IS NULL OR valid_from < ?", checkOut).
+			Where("valid_to IS NULL OR valid_to >= ?", checkIn)
+	}).Where("hotel_id IN ? AND status = ?", hotelIDs, 0).Find(&plans).Error; err != nil {
+		return nil, err
+	}
+
+	rules := &ratePlanRules{byHotel: make(map[uint][]scopedRateRule)}
+	for _, plan := range plans {
+		for _, rule := range plan.Rules {
+			scoped := scopedRateRule{roomCategoryID: plan.RoomCategoryID, rule: rule}
+			if weekdays := rule.GetWeekdays(); len(weekdays) > 0 {
+				scoped.weekdays = make(map[time.Weekday]bool, len(weekdays))
+				for _, day := range weekdays {
+					scoped.weekdays[time.Weekday(day)] = true
+				}
+			}
+			rules.byHotel[plan.HotelID] = append(rules.byHotel[plan.HotelID], scoped)
+		}
+	}
+
+	// Highest priority first; among equals a category's own plan beats a hotel-wide one,
+	// then the most recently added rule wins
+	for _, hotelRules := range rules.byHotel {
+		sort.Slice(hotelRules, func(i, j int) bool {
+			a, b := hotelRules[i], hotelRules[j]
+			if a.rule.Priority != b.rule.Priority {
+				return a.rule.Priority > b.rule.Priority
+			}
+			if (a.roomCategoryID != nil) != (b.roomCategoryID != nil) {
+				return a.roomCategoryID != nil
+			}
+			return a.rule.ID > b.rule.ID
+		})
+	}
+	return rules, nil
+}
+
+// apply returns the room's rate for a night after the rule that applies to it, and false when
+// no rule covers the night
+func (r *ratePlanRules) apply(room *models.Room, night time.Time, rate float64) (float64, bool) {
+	if r == nil {
+		return rate, false
+	}
+	for _, scoped := range r.byHotel[room.HotelID] {
+		if scoped.roomCategoryID != nil && *scoped.roomCategoryID != room.RoomCategoryID {
+			continue
+		}
+		if !scoped.covers(night) {
+			continue
+		}
+
+		switch scoped.rule.Adjustment {
+		case models.RateAdjustmentPercent:
+			rate += rate * scoped.rule.Value / 100
+		case models.RateAdjustmentAmount:
+			rate += scoped.rule.Value
+		case models.RateAdjustmentFixed:
+			rate = scoped.rule.Value
+		}
+		if rate < 0 {
+			rate = 0
+		}
+		return roundAmount(rate), true
+	}
+	return rate, false
+}
+
+func (r *scopedRateRule) covers(night time.Time) bool {
+	if r.rule.ValidFrom != nil && night.Before(dateOnly(*r.rule.ValidFrom)) {
+		return false
+	}
+	if r.rule.ValidTo != nil && night.After(dateOnly(*r.rule.ValidTo)) {
+		return false
+	}
+	return r.weekdays == nil || r.weekdays[night.Weekday()]
+}
+
+// nightlyRoomRate prices one night of a room: a manual override wins, then the applicable rate
+// plan rule, then the room's own rate
+func nightlyRoomRate(room *models.Room, night time.Time, rate float64, overrides map[string]float64, rules *ratePlanRules) NightlyRate {
+	key := night.Format("2006-01-02")
+	if price, ok := overrides[key]; ok {
+		return NightlyRate{Date: key, Price: price, Source: RateSourceOverride}
+	}
+	if price, ok := rules.apply(room, night, rate); ok {
+		return NightlyRate{Date: key, Price: price, Source: RateSourceRatePlan}
+	}
+	return NightlyRate{Date: key, Price: rate, Source: RateSourceRoom}
+}
@@ -2,9 +2,12 @@ package services
 
 import (
 	"flyola-services/internal/models"
+	"fmt"
+	"sort"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type RoomAvailabilityService struct {
@@ -28,18 +31,11 @@ func (s *RoomAvailabilityService) GetAllRoomAvailability(roomID, date string) ([
 		query = query.Where("date = ?", date)
 	}
 	
-	if err := query.Find(&availabilities).Error; err != nil {
+	// Load room, hotel and category data for all records at once
+	if err := query.Preload("Room.Hotel").Preload("Room.RoomCategory").Find(&availabilities).Error; err != nil {
 		return nil, err
 	}
 
-	// Load room and hotel data for each availability record
-	for i := range availabilities {
-		var room models.Room
-		if err := s.db.Preload("Hotel").Preload("RoomCategory").First(&room, availabilities[i].RoomID).Error; err == nil {
-			availabilities[i].Room = room
-		}
-	}
-
 	return availabilities, nil
 }
 
@@ -59,6 +55,11 @@ func (s *RoomAvailabilityService) GetRoomAvailabilityByID(id uint) (*models.Room
 }
 
 func (s *RoomAvailabilityService) CreateRoomAvailability(availability *models.RoomAvailability) error {
+	availability.PriceSource = models.PriceSourceManual
+	availability.BlockSource = ""
+	if !availability.IsAvailable {
+		availability.BlockSource = models.BlockSourceManual
+	}
 	return s.db.Create(availability).Error
 }
 
@@ -68,6 +69,12 @@ func (s *RoomAvailabilityService) UpdateRoomAvailability(id uint, updates *model
 		return nil, err
 	}
 
+	// A price set by hand is no longer kept in line with the room's base price
+	updates.PriceSource = ""
+	if updates.Price > 0 {
+		updates.PriceSource = models.PriceSourceManual
+	}
+
 	if err := s.db.Model(&availability).Updates(updates).Error; err != nil {
 		return nil, err
 	}
@@ -112,16 +119,694 @@ func (s *RoomAvailabilityService) GetAvailableRoomsByDate(hotelID uint, date tim
 	return availableRooms, nil
 }
 
-func (s *RoomAvailabilityService) BulkUpdateAvailability(roomID uint, startDate, endDate time.Time, isAvailable bool, price *float64) error {
-	updates := map[string]interface{}{
-		"is_available": isAvailable,
+// maxBulkAvailabilityDays caps the date range of a single bulk availability update
+const maxBulkAvailabilityDays = 366
+
+// BulkAvailabilityUpdate sets availability and/or price for a set of rooms over a date range.
+// Rooms are given by ID or by room category (limited to one hotel when HotelID is set, which is
+// required for global categories). Both ends of the range are inclusive; Weekdays (0 = Sunday)
+// restricts the update to those days of the week.
+type BulkAvailabilityUpdate struct {
+	RoomIDs        []uint    `json:"roomIds"`
+	HotelID        *uint     `json:"hotelId"`
+	RoomCategoryID *uint     `json:"roomCategoryId"`
+	StartDate      time.Time `json:"startDate" binding:"required"`
+	EndDate        time.Time `json:"endDate" binding:"required"`
+	Weekdays       []int     `json:"weekdays"`
+	IsAvailable    *bool     `json:"isAvailable"`
+	Price          *float64  `json:"price"`
+}
+
+// BulkAvailabilityResult counts the rows written by a bulk availability update. Skipped counts
+// nights left unavailable because a booking or a checkout hold holds them.
+type BulkAvailabilityResult struct {
+	Rooms   int   `json:"rooms"`
+	Nights  int   `json:"nights"`
+	Created int64 `json:"created"`
+	Updated int64 `json:"updated"`
+	Skipped int   `json:"skipped"`
+}
+
+// BulkUpdateAvailability upserts the availability rows matched by update, creating the nights that
+// have none. Rooms are written in batches, each in its own transaction. Nights held by a booking
+// or a checkout hold can be repriced or closed but are never reopened.
+func (s *RoomAvailabilityService) BulkUpdateAvailability(update *BulkAvailabilityUpdate) (*BulkAvailabilityResult, error) {
+	nights, err := validateBulkAvailabilityUpdate(update)
+	if err != nil {
+		return nil, err
 	}
-	
-	if price != nil {
-		updates["price"] = *price
+
+	roomIDs, err := s.bulkUpdateRooms(update)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BulkAvailabilityResult{Rooms: len(roomIDs), Nights: len(nights)}
+	for start := 0; start < len(roomIDs); start += calendarRoomBatch {
+		end := start + calendarRoomBatch
+		if end > len(roomIDs) {
+			end = len(roomIDs)
+		}
+		if err := s.db.Transaction(func(tx *gorm.DB) error {
+			return upsertRoomAvailability(tx, roomIDs[start:end], nights, update, result)
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// validateBulkAvailabilityUpdate checks a bulk update and returns the nights it covers
+func validateBulkAvailabilityUpdate(update *BulkAvailabilityUpdate) ([]time.Time, error) {
+	if len(update.RoomIDs) == 0 && update.RoomCategoryID == nil {
+		return nil, &BookingValidationError{Message: "roomIds or roomCategoryId is required"}
+	}
+	if len(update.RoomIDs) > 0 && update.RoomCategoryID != nil {
+		return nil, &BookingValidationError{Message: "give either roomIds or roomCategoryId, not both"}
+	}
+	if update.IsAvailable == nil && update.Price == nil {
+		return nil, &BookingValidationError{Message: "isAvailable or price is required"}
+	}
+	if update.Price != nil && *update.Price <= 0 {
+		return nil, &BookingValidationError{Message: "price must be greater than 0"}
+	}
+
+	return datesInRange(update.StartDate, update.EndDate, update.Weekdays, maxBulkAvailabilityDays)
+}
+
+// datesInRange lists the dates from startDate to endDate (both inclusive) that fall on one of
+// weekdays (0 = Sunday, empty for every day). The range may span at most maxDays days.
+func datesInRange(startDate, endDate time.Time, weekdays []int, maxDays int) ([]time.Time, error) {
+	startDate, endDate = dateOnly(startDate), dateOnly(endDate)
+	if endDate.Before(startDate) {
+		return nil, &BookingValidationError{Message: "endDate must not be before startDate"}
+	}
+	if endDate.Sub(startDate) >= time.Duration(maxDays)*24*time.Hour {
+		return nil, &BookingValidationError{Message: fmt.Sprintf("date range must not exceed %d days", maxDays)}
+	}
+
+	days := make(map[time.Weekday]bool, len(weekdays))
+	for _, day := range weekdays {
+		if day < 0 || day > 6 {
+			return nil, &BookingValidationError{Message: "weekdays must be between 0 (Sunday) and 6 (Saturday)"}
+		}
+		days[time.Weekday(day)] = true
+	}
+
+	var dates []time.Time
+	for _, date := range stayNights(startDate, endDate.AddDate(0, 0, 1)) {
+		if len(days) == 0 || days[date.Weekday()] {
+			dates = append(dates, date)
+		}
+	}
+	if len(dates) == 0 {
+		return nil, &BookingValidationError{Message: "no dates in range fall on the given weekdays"}
+	}
+	return dates, nil
+}
+
+// bulkUpdateRooms resolves the rooms a bulk update applies to, in ID order
+func (s *RoomAvailabilityService) bulkUpdateRooms(update *BulkAvailabilityUpdate) ([]uint, error) {
+	query := s.db.Model(&models.Room{}).Order("id")
+
+	if update.RoomCategoryID != nil {
+		var category models.RoomCategory
+		if err := s.db.First(&category, *update.RoomCategoryID).Error; err != nil {
+			return nil, err
+		}
+		if category.HotelID == nil && update.HotelID == nil {
+			return nil, &BookingValidationError{Message: "hotelId is required for a global room category"}
+		}
+		query = query.Where("room_category_id = ?", category.ID)
+		if update.HotelID != nil {
+			query = query.Where("hotel_id = ?", *update.HotelID)
+		}
+	} else {
+		query = query.Where("id IN ?", update.RoomIDs)
+		if update.HotelID != nil {
+			query = query.Where("hotel_id = ?", *update.HotelID)
+		}
+	}
+
+	var roomIDs []uint
+	if err := query.Pluck("id", &roomIDs).Error; err != nil {
+		return nil, err
+	}
+
+	if update.RoomCategoryID == nil && len(roomIDs) != len(uniqueIDs(update.RoomIDs)) {
+		return nil, &BookingValidationError{Message: "one or more rooms do not exist or belong to another hotel"}
 	}
+	if len(roomIDs) == 0 {
+		return nil, &BookingValidationError{Message: "no rooms match the room category"}
+	}
+	return roomIDs, nil
+}
+
+// upsertRoomAvailability applies a bulk update to a batch of rooms inside tx
+func upsertRoomAvailability(tx *gorm.DB, roomIDs []uint, nights []time.Time, update *BulkAvailabilityUpdate, result *BulkAvailabilityResult) error {
+	// Lock the rooms like a reservation does, so no booking can take a night while it is reopened
+	var rooms []models.Room
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "base_price").
+		Where("id IN ?", roomIDs).
+		Order("id").
+		Find(&rooms).Error; err != nil {
+		return err
+	}
+
+	first, last := nights[0], nights[len(nights)-1].AddDate(0, 0, 1)
+
+	booked := make(map[uint]map[string]bool, len(rooms))
+	if update.IsAvailable != nil && *update.IsAvailable {
+		var bookings []models.HotelBooking
+		if err := tx.Select("room_id", "check_in_date", "check_out_date").
+			Where("room_id IN ? AND booking_status IN ?", roomIDs, inventoryHoldingStatuses).
+			Where("check_in_date < ? AND check_out_date > ?", last, first).
+			Find(&bookings).Error; err != nil {
+			return err
+		}
+		for _, booking := range bookings {
+			roomID := *booking.RoomID
+			if booked[roomID] == nil {
+				booked[roomID] = make(map[string]bool)
+			}
+			for _, night := range stayNights(dateOnly(booking.CheckInDate), dateOnly(booking.CheckOutDate)) {
+				booked[roomID][night.Format("2006-01-02")] = true
+			}
+		}
+	}
+
+	var existing []models.RoomAvailability
+	if err := tx.Select("id", "room_id", "date", "block_source").
+		Where("room_id IN ? AND date >= ? AND date < ?", roomIDs, first, last).
+		Find(&existing).Error; err != nil {
+		return err
+	}
+	rows := make(map[uint]map[string]uint, len(rooms))
+	for _, availability := range existing {
+		key := availability.Date.Format("2006-01-02")
+		if rows[availability.RoomID] == nil {
+			rows[availability.RoomID] = make(map[string]uint)
+		}
+		rows[availability.RoomID][key] = availability.ID
 
-	return s.db.Model(&models.RoomAvailability{}).
-		Where("room_id = ? AND date BETWEEN ? AND ?", roomID, startDate, endDate).
-		Updates(updates).Error
-}
\ No newline at end of file
+		// Nights reserved by a checkout hold have no booking row but are just as taken
+		if update.IsAvailable != nil && *update.IsAvailable && availability.BlockSource == models.BlockSourceBooking {
+			if booked[availability.RoomID] == nil {
+				booked[availability.RoomID] = make(map[string]bool)
+			}
+			booked[availability.RoomID][key] = true
+		}
+	}
+
+	var updateIDs, priceOnlyIDs []uint
+	var missing []models.RoomAvailability
+	for _, room := range rooms {
+		for _, night := range nights {
+			key := night.Format("2006-01-02")
+			isBooked := booked[room.ID][key]
+			if isBooked {
+				result.Skipped++
+			}
+
+			if id, ok := rows[room.ID][key]; ok {
+				if isBooked {
+					if update.Price != nil {
+						priceOnlyIDs = append(priceOnlyIDs, id)
+					}
+				} else {
+					updateIDs = append(updateIDs, id)
+				}
+				continue
+			}
+
+			availability := models.RoomAvailability{
+				RoomID:      room.ID,
+				Date:        night,
+				IsAvailable: !isBooked,
+				Price:       room.BasePrice,
+				PriceSource: models.PriceSourceBase,
+			}
+			if isBooked {
+				availability.BlockSource = models.BlockSourceBooking
+			}
+			if update.IsAvailable != nil && !*update.IsAvailable {
+				availability.IsAvailable = false
+				availability.BlockSource = models.BlockSourceManual
+			}
+			if update.Price != nil {
+				availability.Price = *update.Price
+				availability.PriceSource = models.PriceSourceManual
+			}
+			missing = append(missing, availability)
+		}
+	}
+
+	updates := map[string]interface{}{}
+	if update.IsAvailable != nil {
+		// A night closed by hand stays closed until it is opened by hand
+		updates["is_available"] = *update.IsAvailable
+		updates["block_source"] = nil
+		if !*update.IsAvailable {
+			updates["block_source"] = models.BlockSourceManual
+		}
+	}
+	if update.Price != nil {
+		updates["price"] = *update.Price
+		updates["price_source"] = models.PriceSourceManual
+	}
+	if err := updateAvailabilityRows(tx, updateIDs, updates, result); err != nil {
+		return err
+	}
+	if len(priceOnlyIDs) > 0 {
+		priceUpdates := map[string]interface{}{"price": *update.Price, "price_source": models.PriceSourceManual}
+		if err := updateAvailabilityRows(tx, priceOnlyIDs, priceUpdates, result); err != nil {
+			return err
+		}
+	}
+
+	if len(missing) > 0 {
+		res := tx.Omit("Room").CreateInBatches(&missing, bulkWriteBatch)
+		if res.Error != nil {
+			return res.Error
+		}
+		result.Created += res.RowsAffected
+	}
+
+	return nil
+}
+
+// updateAvailabilityRows applies updates to the given availability rows in batches
+func updateAvailabilityRows(tx *gorm.DB, ids []uint, updates map[string]interface{}, result *BulkAvailabilityResult) error {
+	for start := 0; start < len(ids); start += bulkWriteBatch {
+		end := start + bulkWriteBatch
+		if end > len(ids) {
+			end = len(ids)
+		}
+		res := tx.Model(&models.RoomAvailability{}).Where("id IN ?", ids[start:end]).Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+		result.Updated += res.RowsAffected
+	}
+	return nil
+}
+
+// uniqueIDs returns ids without duplicates
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// maxMatrixDays caps the number of dates in an availability matrix
+const maxMatrixDays = 93
+
+// AvailabilityMatrix is a room by date grid of a hotel's inventory
+type AvailabilityMatrix struct {
+	HotelID uint         `json:"hotelId"`
+	Dates   []string     `json:"dates"`
+	Rooms   []MatrixRoom `json:"rooms"`
+}
+
+// MatrixRoom is one row of an availability matrix, with a cell per date
+type MatrixRoom struct {
+	RoomID         uint         `json:"roomId"`
+	RoomNumber     string       `json:"roomNumber"`
+	RoomCategoryID uint         `json:"roomCategoryId"`
+	RoomCategory   string       `json:"roomCategory"`
+	Cells          []MatrixCell `json:"cells"`
+}
+
+// MatrixCell is the state of one room on one night
+type MatrixCell struct {
+	Date             string             `json:"date"`
+	IsAvailable      bool               `json:"isAvailable"`
+	Price            float64            `json:"price"`
+	PriceSource      string             `json:"priceSource"`
+	BookingID        *uint              `json:"bookingId,omitempty"`
+	BookingReference string             `json:"bookingReference,omitempty"`
+	BookingStatus    string             `json:"bookingStatus,omitempty"`
+	Restrictions     *MatrixRestriction `json:"restrictions,omitempty"`
+}
+
+// MatrixRestriction holds the stay restrictions of a room's category on a date
+type MatrixRestriction struct {
+	MinStay           int  `json:"minStay,omitempty"`
+	MaxStay           int  `json:"maxStay,omitempty"`
+	ClosedToArrival   bool `json:"closedToArrival,omitempty"`
+	ClosedToDeparture bool `json:"closedToDeparture,omitempty"`
+	StopSell          bool `json:"stopSell,omitempty"`
+}
+
+// GetAvailabilityMatrix builds the room by date grid of a hotel's active rooms from one date to
+// another (both inclusive) in a fixed number of queries, whatever the size of the hotel. Nights
+// without an availability row are open and priced by the room's rate plans.
+func (s *RoomAvailabilityService) GetAvailabilityMatrix(hotelID uint, from, to time.Time) (*AvailabilityMatrix, error) {
+	dates, err := datesInRange(from, to, nil, maxMatrixDays)
+	if err != nil {
+		return nil, err
+	}
+	from, end := dates[0], dates[len(dates)-1].AddDate(0, 0, 1)
+
+	if err := s.db.Select("id").First(&models.Hotel{}, hotelID).Error; err != nil {
+		return nil, err
+	}
+
+	matrix := &AvailabilityMatrix{HotelID: hotelID, Dates: make([]string, len(dates)), Rooms: []MatrixRoom{}}
+	for i, date := range dates {
+		matrix.Dates[i] = date.Format("2006-01-02")
+	}
+
+	var rooms []models.Room
+	if err := s.db.Preload("RoomCategory").
+		Where("hotel_id = ? AND status = ?", hotelID, 0).
+		Order("room_category_id, room_number, id").Find(&rooms).Error; err != nil {
+		return nil, err
+	}
+	if len(rooms) == 0 {
+		return matrix, nil
+	}
+
+	roomIDs := make([]uint, len(rooms))
+	categoryIDs := make([]uint, 0, len(rooms))
+	seenCategory := make(map[uint]bool)
+	for i, room := range rooms {
+		roomIDs[i] = room.ID
+		if !seenCategory[room.RoomCategoryID] {
+			seenCategory[room.RoomCategoryID] = true
+			categoryIDs = append(categoryIDs, room.RoomCategoryID)
+		}
+	}
+
+	var availabilities []models.RoomAvailability
+	if err := s.db.Where("room_id IN ? AND date >= ? AND date < ?", roomIDs, from, end).
+		Find(&availabilities).Error; err != nil {
+		return nil, err
+	}
+	rows := make(map[uint]map[string]models.RoomAvailability, len(rooms))
+	overrides := make(map[uint]map[string]float64, len(rooms))
+	for _, availability := range availabilities {
+		key := availability.Date.Format("2006-01-02")
+		if rows[availability.RoomID] == nil {
+			rows[availability.RoomID] = make(map[string]models.RoomAvailability)
+			overrides[availability.RoomID] = make(map[string]float64)
+		}
+		rows[availability.RoomID][key] = availability
+		if availability.PriceSource != models.PriceSourceBase && availability.Price > 0 {
+			overrides[availability.RoomID][key] = availability.Price
+		}
+	}
+
+	var bookings []models.HotelBooking
+	if err := s.db.Select("id", "room_id", "booking_reference", "booking_status", "check_in_date", "check_out_date").
+		Where("room_id IN ? AND booking_status IN ?", roomIDs, inventoryHoldingStatuses).
+		Where("check_in_date < ? AND check_out_date > ?", end, from).
+		Find(&bookings).Error; err != nil {
+		return nil, err
+	}
+	occupied := make(map[uint]map[string]*models.HotelBooking)
+	for i := range bookings {
+		booking := &bookings[i]
+		roomID := *booking.RoomID
+		if occupied[roomID] == nil {
+			occupied[roomID] = make(map[string]*models.HotelBooking)
+		}
+		for _, night := range stayNights(dateOnly(booking.CheckInDate), dateOnly(booking.CheckOutDate)) {
+			occupied[roomID][night.Format("2006-01-02")] = booking
+		}
+	}
+
+	restrictions, err := loadStayRestrictions(s.db, []uint{hotelID}, categoryIDs, from, end)
+	if err != nil {
+		return nil, err
+	}
+	restrictionsByDate := make(map[uint]map[string]*MatrixRestriction)
+	for categoryID, categoryRestrictions := range restrictions[hotelID] {
+		restrictionsByDate[categoryID] = make(map[string]*MatrixRestriction)
+		for _, restriction := range categoryRestrictions {
+			restrictionsByDate[categoryID][restriction.Date.Format("2006-01-02")] = &MatrixRestriction{
+				MinStay:           restriction.MinStay,
+				MaxStay:           restriction.MaxStay,
+				ClosedToArrival:   restriction.ClosedToArrival,
+				ClosedToDeparture: restriction.ClosedToDeparture,
+				StopSell:          restriction.StopSell,
+			}
+		}
+	}
+
+	ratePlans, err := loadRatePlanRules(s.db, []uint{hotelID}, from, end)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range rooms {
+		room := &rooms[i]
+		row := MatrixRoom{
+			RoomID:         room.ID,
+			RoomNumber:     room.RoomNumber,
+			RoomCategoryID: room.RoomCategoryID,
+			RoomCategory:   room.RoomCategory.Name,
+			Cells:          make([]MatrixCell, 0, len(dates)),
+		}
+		for _, date := range dates {
+			key := date.Format("2006-01-02")
+			nightly := nightlyRoomRate(room, date, room.BasePrice, overrides[room.ID], ratePlans)
+			cell := MatrixCell{
+				Date:         key,
+				IsAvailable:  true,
+				Price:        nightly.Price,
+				PriceSource:  nightly.Source,
+				Restrictions: restrictionsByDate[room.RoomCategoryID][key],
+			}
+			if availability, ok := rows[room.ID][key]; ok {
+				cell.IsAvailable = availability.IsAvailable
+			}
+			if booking, ok := occupied[room.ID][key]; ok {
+				cell.IsAvailable = false
+				cell.BookingID = &booking.ID
+				cell.BookingReference = booking.BookingReference
+				cell.BookingStatus = booking.BookingStatus
+			}
+			row.Cells = append(row.Cells, cell)
+		}
+		matrix.Rooms = append(matrix.Rooms, row)
+	}
+
+	return matrix, nil
+}
+
+// calendarRoomBatch is how many rooms the calendar generator and bulk updates handle per transaction
+const calendarRoomBatch = 50
+
+// bulkWriteBatch is how many availability rows are inserted or updated per statement
+const bulkWriteBatch = 500
+
+// CalendarResult summarises a run of the availability calendar generator
+type CalendarResult struct {
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Rooms    int       `json:"rooms"`
+	Created  int64     `json:"created"`
+	Repriced int64     `json:"repriced"`
+}
+
+// GenerateCalendar makes sure every active room (of one hotel, or of every hotel when hotelID is nil)
+// has an availability row for each of the next days nights. Missing nights are created available
+// and priced from Room.BasePrice; existing rows priced from the base price follow it when it changes.
+// Rows set by hand or blocked by bookings keep their availability and manual prices.
+func (s *RoomAvailabilityService) GenerateCalendar(hotelID *uint, days int) (*CalendarResult, error) {
+	if days <= 0 {
+		return nil, &BookingValidationError{Message: "days must be greater than 0"}
+	}
+
+	if hotelID != nil {
+		var hotel models.Hotel
+		if err := s.db.Select("id").First(&hotel, *hotelID).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	from := dateOnly(time.Now())
+	result := &CalendarResult{From: from, To: from.AddDate(0, 0, days)}
+
+	query := s.db.Model(&models.Room{}).
+		Select("rooms.id", "rooms.base_price").
+		Joins("JOIN hotels ON hotels.id = rooms.hotel_id").
+		Where("rooms.status = ? AND hotels.status = ?", 0, 0)
+	if hotelID != nil {
+		query = query.Where("rooms.hotel_id = ?", *hotelID)
+	}
+
+	var rooms []models.Room
+	err := query.FindInBatches(&rooms, calendarRoomBatch, func(batch *gorm.DB, _ int) error {
+		created, repriced, err := s.generateRoomCalendars(rooms, result.From, result.To)
+		if err != nil {
+			return err
+		}
+		result.Rooms += len(rooms)
+		result.Created += created
+		result.Repriced += repriced
+		return nil
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// generateRoomCalendars fills in and reprices the calendar of a batch of rooms in one transaction
+func (s *RoomAvailabilityService) generateRoomCalendars(rooms []models.Room, from, to time.Time) (int64, int64, error) {
+	roomIDs := make([]uint, len(rooms))
+	for i, room := range rooms {
+		roomIDs[i] = room.ID
+	}
+
+	var created, repriced int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var existing []models.RoomAvailability
+		if err := tx.Select("room_id", "date").
+			Where("room_id IN ? AND date >= ? AND date < ?", roomIDs, from, to).
+			Find(&existing).Error; err != nil {
+			return err
+		}
+		present := make(map[uint]map[string]bool, len(rooms))
+		for _, availability := range existing {
+			if present[availability.RoomID] == nil {
+				present[availability.RoomID] = make(map[string]bool)
+			}
+			present[availability.RoomID][availability.Date.Format("2006-01-02")] = true
+		}
+
+		var missing []models.RoomAvailability
+		for _, room := range rooms {
+			for _, night := range stayNights(from, to) {
+				if present[room.ID][night.Format("2006-01-02")] {
+					continue
+				}
+				missing = append(missing, models.RoomAvailability{
+					RoomID:      room.ID,
+					Date:        night,
+					IsAvailable: true,
+					Price:       room.BasePrice,
+					PriceSource: models.PriceSourceBase,
+				})
+			}
+		}
+		if len(missing) > 0 {
+			// A booking or another replica may have added some of the nights meanwhile
+			res := tx.Omit("Room").Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&missing, bulkWriteBatch)
+			if res.Error != nil {
+				return res.Error
+			}
+			created = res.RowsAffected
+		}
+
+		res := tx.Exec(`UPDATE room_availability a JOIN rooms r ON r.id = a.room_id
+			SET a.price = r.base_price, a.updated_at = ?
+			WHERE a.room_id IN ? AND a.date >= ? AND a.price_source = ? AND a.price <> r.base_price`,
+			time.Now(), roomIDs, from, models.PriceSourceBase)
+		if res.Error != nil {
+			return res.Error
+		}
+		repriced = res.RowsAffected
+		return nil
+	})
+
+	return created, repriced, err
+}
+
+// reserveRoomNights locks a room and marks every night of the stay unavailable. It fails with a
+// BookingConflictError if another active booking overlaps the stay or any night is already blocked.
+// excludeBookingID lets a booking being modified ignore its own nights.
+func reserveRoomNights(tx *gorm.DB, roomID uint, checkIn, checkOut time.Time, excludeBookingID uint) error {
+	// Lock the room row first so concurrent reservations for the same room are serialised,
+	// even for nights that have no availability row yet
+	var room models.Room
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&room, roomID).Error; err != nil {
+		return err
+	}
+
+	conflicts := make(map[string]bool)
+
+	var overlapping []models.HotelBooking
+	if err := tx.Select("id", "check_in_date", "check_out_date").
+		Where("room_id = ? AND id <> ? AND booking_status IN ?", roomID, excludeBookingID, inventoryHoldingStatuses).
+		Where("check_in_date < ? AND check_out_date > ?", checkOut, checkIn).
+		Find(&overlapping).Error; err != nil {
+		return err
+	}
+	for _, other := range overlapping {
+		for _, night := range stayNights(dateOnly(other.CheckInDate), dateOnly(other.CheckOutDate)) {
+			if !night.Before(checkIn) && night.Before(checkOut) {
+				conflicts[night.Format("2006-01-02")] = true
+			}
+		}
+	}
+
+	var availabilities []models.RoomAvailability
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("room_id = ? AND date >= ? AND date < ?", roomID, checkIn, checkOut).
+		Find(&availabilities).Error; err != nil {
+		return err
+	}
+	existing := make(map[string]bool, len(availabilities))
+	for _, availability := range availabilities {
+		key := availability.Date.Format("2006-01-02")
+		existing[key] = true
+		if !availability.IsAvailable {
+			conflicts[key] = true
+		}
+	}
+
+	if len(conflicts) > 0 {
+		dates := make([]string, 0, len(conflicts))
+		for date := range conflicts {
+			dates = append(dates, date)
+		}
+		sort.Strings(dates)
+		return &BookingConflictError{RoomID: roomID, Dates: dates}
+	}
+
+	if err := tx.Model(&models.RoomAvailability{}).
+		Where("room_id = ? AND date >= ? AND date < ?", roomID, checkIn, checkOut).
+		Updates(map[string]interface{}{"is_available": false, "block_source": models.BlockSourceBooking}).Error; err != nil {
+		return err
+	}
+
+	// Nights without an availability row get one, so the calendar reflects the booking
+	for _, night := range stayNights(checkIn, checkOut) {
+		if existing[night.Format("2006-01-02")] {
+			continue
+		}
+		availability := models.RoomAvailability{
+			RoomID:      roomID,
+			Date:        night,
+			IsAvailable: false,
+			PriceSource: models.PriceSourceBase,
+			BlockSource: models.BlockSourceBooking,
+		}
+		if err := tx.Omit("Room").Create(&availability).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// releaseRoomNights makes the nights of a stay available again. Nights an external calendar or a
+// manual close still blocks stay closed; untagged nights were reserved before bookings tagged theirs.
+func releaseRoomNights(tx *gorm.DB, roomID uint, checkIn, checkOut time.Time) error {
+	return tx.Model(&models.RoomAvailability{}).
+		Where("room_id = ? AND date >= ? AND date < ?", roomID, dateOnly(checkIn), dateOnly(checkOut)).
+		Where("block_source IS NULL OR block_source = ?", models.BlockSourceBooking).
+		Updates(map[string]interface{}{"is_available": true, "block_source": nil}).Error
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"flyola-services/internal/models"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// maxSearchNights bounds the length of a stay that can be searched
+const maxSearchNights = 30
+
+// HotelSearch is a search for hotels in a city with a room free for the whole stay. Children
+// without ChildAges are counted and priced as adults.
+type HotelSearch struct {
+	CityID      uint
+	CheckIn     time.Time
+	CheckOut    time.Time
+	Adults      int
+	Children    int
+	ChildAges   []int
+	StarRatings []int
+	Amenities   []string
+}
+
+// HotelSearchResult is a hotel with at least one room category free on every night of the stay
+type HotelSearchResult struct {
+	Hotel         models.Hotel    `json:"hotel"`
+	CheapestPrice float64         `json:"cheapest_price"`
+	Categories    []CategoryOffer `json:"categories"`
+}
+
+// CategoryOffer is the cheapest free room of a category for the stay. RoomID is the room to book.
+// AllotmentRooms counts the rooms that can instead be booked by room_category_id from the
+// category's allotment.
+type CategoryOffer struct {
+	RoomCategory   models.RoomCategory `json:"room_category"`
+	AvailableRooms int                 `json:"available_rooms"`
+	AllotmentRooms int                 `json:"allotment_rooms,omitempty"`
+	RoomID         uint                `json:"room_id"`
+	Price          *PriceBreakdown     `json:"price"`
+	MealPlans      []MealPlanOffer     `json:"meal_plans,omitempty"`
+}
+
+type SearchService struct {
+	db             *gorm.DB
+	bookingService *BookingService
+}
+
+func NewSearchService(db *gorm.DB, bookingService *BookingService) *SearchService {
+	return &SearchService{db: db, bookingService: bookingService}
+}
+
+// SearchHotels finds the hotels of a city that can host the party for the whole stay. Free rooms
+// are found in a single query; prices, overrides and meal plans are then loaded for all of them
+// at once, so the number of queries does not grow with the number of rooms.
+func (s *SearchService) SearchHotels(search *HotelSearch) ([]HotelSearchResult, error) {
+	checkIn := dateOnly(search.CheckIn)
+	checkOut := dateOnly(search.CheckOut)
+	if !checkOut.After(checkIn) {
+		return nil, &BookingValidationError{Message: "check_out must be after check_in"}
+	}
+	if checkIn.Before(dateOnly(time.Now())) {
+		return nil, &BookingValidationError{Message: "check_in cannot be in the past"}
+	}
+	nights := stayNights(checkIn, checkOut)
+	if len(nights) > maxSearchNights {
+		return nil, &BookingValidationError{Message: fmt.Sprintf("Stays longer than %d nights cannot be searched", maxSearchNights)}
+	}
+	if search.Adults < 1 {
+		return nil, &BookingValidationError{Message: "At least one adult is required"}
+	}
+	if len(search.ChildAges) > 0 {
+		if search.Children != 0 && search.Children != len(search.ChildAges) {
+			return nil, &BookingValidationError{Message: "children must match the number of child_ages"}
+		}
+		search.Children = len(search.ChildAges)
+	}
+	guests := search.Adults + search.Children
+
+	rooms, err := s.freeRooms(search, checkIn, checkOut)
+	if err != nil || len(rooms) == 0 {
+		return []HotelSearchResult{}, err
+	}
+
+	roomIDs := make([]uint, len(rooms))
+	categoryIDs := make([]uint, 0, len(rooms))
+	hotelIDs := make([]uint, 0, len(rooms))
+	seenCategory := make(map[uint]bool)
+	seenHotel := make(map[uint]bool)
+	for i, room := range rooms {
+		roomIDs[i] = room.ID
+		if !seenHotel[room.HotelID] {
+			seenHotel[room.HotelID] = true
+			hotelIDs = append(hotelIDs, room.HotelID)
+		}
+		if !seenCategory[room.RoomCategoryID] {
+			seenCategory[room.RoomCategoryID] = true
+			categoryIDs = append(categoryIDs, room.RoomCategoryID)
+		}
+	}
+
+	overrides, err := roomPriceOverrides(s.db, roomIDs, checkIn, checkOut)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := loadRatePlanRules(s.db, hotelIDs, checkIn, checkOut)
+	if err != nil {
+		return nil, err
+	}
+	restrictions, err := loadStayRestrictions(s.db, hotelIDs, categoryIDs, checkIn, checkOut)
+	if err != nil {
+		return nil, err
+	}
+	restricted := make(map[uint]map[uint]bool)
+	for hotelID, categories := range restrictions {
+		restricted[hotelID] = make(map[uint]bool)
+		for categoryID, categoryRestrictions := range categories {
+			restricted[hotelID][categoryID] = stayRestrictionViolation(categoryRestrictions, checkIn, checkOut) != nil
+		}
+	}
+	mealPlans, err := mealPlanOffers(s.db, categoryIDs, checkIn, checkOut)
+	if err != nil {
+		return nil, err
+	}
+	allotments, err := allotmentRoomsLeft(s.db, hotelIDs, categoryIDs, checkIn, checkOut)
+	if err != nil {
+		return nil, err
+	}
+	policies, err := loadChildPolicies(s.db, hotelIDs)
+	if err != nil {
+		return nil, err
+	}
+	parties := make(map[uint]*stayParty, len(hotelIDs))
+	for _, hotelID := range hotelIDs {
+		// Hotels whose child policy cannot accept the party (no adult left) get no party and are skipped
+		parties[hotelID], _ = splitParty(policies[hotelID], guests, search.ChildAges)
+	}
+
+	results := make(map[uint]*HotelSearchResult)
+	offers := make(map[uint]map[uint]*CategoryOffer)
+	var hotelOrder []uint
+	for i := range rooms {
+		room := &rooms[i]
+		if restricted[room.HotelID][room.RoomCategoryID] {
+			// Categories whose stay restrictions rule out this stay are not offered
+			continue
+		}
+		party := parties[room.HotelID]
+		if party == nil {
+			continue
+		}
+		breakdown, err := s.bookingService.roomBreakdown(room, party, nights, overrides[room.ID], rules)
+		if err != nil {
+			// Rooms that cannot host the party or have no price are simply not offered
+			continue
+		}
+
+		result, ok := results[room.HotelID]
+		if !ok {
+			result = &HotelSearchResult{Hotel: room.Hotel}
+			results[room.HotelID] = result
+			offers[room.HotelID] = make(map[uint]*CategoryOffer)
+			hotelOrder = append(hotelOrder, room.HotelID)
+		}
+
+		offer, ok := offers[room.HotelID][room.RoomCategoryID]
+		if !ok {
+			offer = &CategoryOffer{
+				RoomCategory:   room.RoomCategory,
+				AllotmentRooms: allotments[room.HotelID][room.RoomCategoryID],
+				MealPlans:      mealPlans[room.RoomCategoryID],
+			}
+			offers[room.HotelID][room.RoomCategoryID] = offer
+		}
+		offer.AvailableRooms++
+		if offer.Price == nil || breakdown.FinalAmount < offer.Price.FinalAmount {
+			offer.RoomID = room.ID
+			offer.Price = breakdown
+		}
+	}
+
+	hotels := make([]HotelSearchResult, 0, len(hotelOrder))
+	for _, hotelID := range hotelOrder {
+		result := results[hotelID]
+		for _, offer := range offers[hotelID] {
+			result.Categories = append(result.Categories, *offer)
+		}
+		sort.Slice(result.Categories, func(i, j int) bool {
+			return result.Categories[i].Price.FinalAmount < result.Categories[j].Price.FinalAmount
+		})
+		result.CheapestPrice = result.Categories[0].Price.FinalAmount
+		hotels = append(hotels, *result)
+	}
+	sort.SliceStable(hotels, func(i, j int) bool {
+		return hotels[i].CheapestPrice < hotels[j].CheapestPrice
+	})
+
+	return hotels, nil
+}
+
+// freeRooms returns the active rooms of the city's hotels that are big enough for the adults and
+// have neither a booking nor a blocked night during the stay. Whether children count towards
+// occupancy depends on each hotel's child policy, so the whole party is checked when pricing.
+func (s *SearchService) freeRooms(search *HotelSearch, checkIn, checkOut time.Time) ([]models.Room, error) {
+	extraPersons := search.Adults - 2
+	if extraPersons < 0 {
+		extraPersons = 0
+	}
+
+	query := s.db.Model(&models.Room{}).Select("rooms.*").
+		Joins("JOIN hotels ON hotels.id = rooms.hotel_id").
+		Joins("JOIN room_categories ON room_categories.id = rooms.room_category_id").
+		Where("hotels.city_id = ? AND hotels.status = ? AND rooms.status = ? AND room_categories.status = ?", search.CityID, 0, 0, 0).
+		Where("COALESCE(room_categories.max_occupancy, 0) = 0 OR room_categories.max_occupancy >= ?", search.Adults).
+		Where("rooms.max_extra_persons >= ?", extraPersons).
+		Where(`NOT EXISTS (SELECT 1 FROM hotel_bookings b WHERE b.room_id = rooms.id AND b.booking_status IN ?
+			AND b.check_in_date < ? AND b.check_out_date > ? AND b.deleted_at IS NULL)`, inventoryHoldingStatuses, checkOut, checkIn).
+		Where(`NOT EXISTS (SELECT 1 FROM room_availability a WHERE a.room_id = rooms.id
+			AND a.date >= ? AND a.date < ? AND a.is_available = ?)`, checkIn, checkOut, false)
+
+	if len(search.StarRatings) > 0 {
+		query = query.Where("hotels.star_rating IN ?", search.StarRatings)
+	}
+	for _, amenity := range search.Amenities {
+		if amenity = strings.TrimSpace(amenity); amenity != "" {
+			query = query.Where("JSON_CONTAINS(hotels.amenities, JSON_QUOTE(?))", amenity)
+		}
+	}
+
+	var rooms []models.Room
+	err := query.Preload("Hotel.City").Preload("RoomCategory").Order("rooms.hotel_id, rooms.id").Find(&rooms).Error
+	return rooms, err
+}
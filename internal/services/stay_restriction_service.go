@@ -0,0 +1,216 @@
+package services
+
+import (
+	"flyola-services/internal/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxRestrictionDays caps the date range of a single stay restriction update or listing
+const maxRestrictionDays = 366
+
+// Stay restriction rules named by a RestrictionViolationError
+const (
+	RestrictionMinStay           = "min_stay"
+	RestrictionMaxStay           = "max_stay"
+	RestrictionClosedToArrival   = "closed_to_arrival"
+	RestrictionClosedToDeparture = "closed_to_departure"
+	RestrictionStopSell          = "stop_sell"
+)
+
+// RestrictionViolationError is returned when a stay breaks a stay restriction of its room category
+type RestrictionViolationError struct {
+	Rule    string
+	Date    string
+	Message string
+}
+
+func (e *RestrictionViolationError) Error() string {
+	return e.Message
+}
+
+// StayRestrictionUpdate sets the same restrictions on every date of a range (both ends inclusive),
+// optionally only on some weekdays (0 = Sunday). All-zero restrictions clear the dates.
+type StayRestrictionUpdate struct {
+	RoomCategoryID    uint      `json:"roomCategoryId" binding:"required"`
+	StartDate         time.Time `json:"startDate" binding:"required"`
+	EndDate           time.Time `json:"endDate" binding:"required"`
+	Weekdays          []int     `json:"weekdays"`
+	MinStay           int       `json:"minStay"`
+	MaxStay           int       `json:"maxStay"`
+	ClosedToArrival   bool      `json:"closedToArrival"`
+	ClosedToDeparture bool      `json:"closedToDeparture"`
+	StopSell          bool      `json:"stopSell"`
+}
+
+type StayRestrictionService struct {
+	db *gorm.DB
+}
+
+func NewStayRestrictionService(db *gorm.DB) *StayRestrictionService {
+	return &StayRestrictionService{db: db}
+}
+
+// GetRestrictions lists a hotel's stay restrictions from one date to another (both inclusive),
+// optionally for a single room category
+func (s *StayRestrictionService) GetRestrictions(hotelID uint, roomCategoryID *uint, from, to time.Time) ([]models.StayRestriction, error) {
+	if _, err := datesInRange(from, to, nil, maxRestrictionDays); err != nil {
+		return nil, err
+	}
+
+	query := s.db.Where("hotel_id = ? AND date >= ? AND date <= ?", hotelID, dateOnly(from), dateOnly(to))
+	if roomCategoryID != nil {
+		query = query.Where("room_category_id = ?", *roomCategoryID)
+	}
+
+	var restrictions []models.StayRestriction
+	err := query.Order("date, room_category_id").Find(&restrictions).Error
+	return restrictions, err
+}
+
+// SetRestrictions writes the restrictions of update for a hotel's room category and returns how
+// many dates were set or cleared
+func (s *StayRestrictionService) SetRestrictions(hotelID uint, update *StayRestrictionUpdate) (int, error) {
+	if update.MinStay < 0 || update.MaxStay < 0 {
+		return 0, &BookingValidationError{Message: "minStay and maxStay cannot be negative"}
+	}
+	if update.MinStay > 0 && update.MaxStay > 0 && update.MaxStay < update.MinStay {
+		return 0, &BookingValidationError{Message: "maxStay must not be less than minStay"}
+	}
+	dates, err := datesInRange(update.StartDate, update.EndDate, update.Weekdays, maxRestrictionDays)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.db.Select("id").First(&models.Hotel{}, hotelID).Error; err != nil {
+		return 0, err
+	}
+	var category models.RoomCategory
+	if err := s.db.Select("id", "hotel_id").First(&category, update.RoomCategoryID).Error; err != nil {
+		return 0, err
+	}
+	if category.HotelID != nil && *category.HotelID != hotelID {
+		return 0, &BookingValidationError{Message: "roomCategoryId belongs to another hotel"}
+	}
+
+	clear := update.MinStay == 0 && update.MaxStay == 0 && !update.ClosedToArrival && !update.ClosedToDeparture && !update.StopSell
+	if clear {
+		err := s.db.Where("hotel_id = ? AND room_category_id = ? AND date IN ?", hotelID, category.ID, dates).
+			Delete(&models.StayRestriction{}).Error
+		return len(dates), err
+	}
+
+	restrictions := make([]models.StayRestriction, len(dates))
+	for i, date := range dates {
+		restrictions[i] = models.StayRestriction{
+			HotelID:           hotelID,
+			RoomCategoryID:    category.ID,
+			Date:              date,
+			MinStay:           update.MinStay,
+			MaxStay:           update.MaxStay,
+			ClosedToArrival:   update.ClosedToArrival,
+			ClosedToDeparture: update.ClosedToDeparture,
+			StopSell:          update.StopSell,
+		}
+	}
+	err = s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "hotel_id"}, {Name: "room_category_id"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"min_stay", "max_stay", "closed_to_arrival", "closed_to_departure", "stop_sell", "updated_at",
+		}),
+	}).CreateInBatches(&restrictions, bulkWriteBatch).Error
+	return len(dates), err
+}
+
+func (s *StayRestrictionService) DeleteRestriction(id uint) error {
+	return s.db.Delete(&models.StayRestriction{}, id).Error
+}
+
+// checkStayRestrictions fails with a RestrictionViolationError if a stay in a hotel's room
+// category breaks one of its restrictions
+func checkStayRestrictions(tx *gorm.DB, hotelID, roomCategoryID uint, checkIn, checkOut time.Time) error {
+	var restrictions []models.StayRestriction
+	if err := tx.Where("hotel_id = ? AND room_category_id = ? AND date >= ? AND date <= ?", hotelID, roomCategoryID, checkIn, checkOut).
+		Order("date").Find(&restrictions).Error; err != nil {
+		return err
+	}
+	if violation := stayRestrictionViolation(restrictions, checkIn, checkOut); violation != nil {
+		return violation
+	}
+	return nil
+}
+
+// loadStayRestrictions loads the restrictions of several hotels' room categories that may affect
+// a stay, keyed by hotel and then room category
+func loadStayRestrictions(tx *gorm.DB, hotelIDs, roomCategoryIDs []uint, checkIn, checkOut time.Time) (map[uint]map[uint][]models.StayRestriction, error) {
+	var restrictions []models.StayRestriction
+	if err := tx.Where("hotel_id IN ? AND room_category_id IN ? AND date >= ? AND date <= ?", hotelIDs, roomCategoryIDs, checkIn, checkOut).
+		Order("date").Find(&restrictions).Error; err != nil {
+		return nil, err
+	}
+
+	byHotel := make(map[uint]map[uint][]models.StayRestriction)
+	for _, restriction := range restrictions {
+		if byHotel[restriction.HotelID] == nil {
+			byHotel[restriction.HotelID] = make(map[uint][]models.StayRestriction)
+		}
+		byHotel[restriction.HotelID][restriction.RoomCategoryID] = append(byHotel[restriction.HotelID][restriction.RoomCategoryID], restriction)
+	}
+	return byHotel, nil
+}
+
+// stayRestrictionViolation returns the first restriction, by date, that a stay breaks. Restrictions
+// must be ordered by date. Min and max stay apply to every night of the stay, so a stay touching a
+// festival night needs that night's minimum; the departure date only counts for closed-to-departure.
+func stayRestrictionViolation(restrictions []models.StayRestriction, checkIn, checkOut time.Time) *RestrictionViolationError {
+	nights := len(stayNights(checkIn, checkOut))
+	for _, restriction := range restrictions {
+		date := dateOnly(restriction.Date)
+		key := date.Format("2006-01-02")
+
+		if date.Equal(checkOut) {
+			if restriction.ClosedToDeparture {
+				return &RestrictionViolationError{
+					Rule:    RestrictionClosedToDeparture,
+					Date:    key,
+					Message: fmt.Sprintf("Departures are not allowed on %s", key),
+				}
+			}
+			continue
+		}
+		if date.Before(checkIn) || date.After(checkOut) {
+			continue
+		}
+
+		switch {
+		case restriction.StopSell:
+			return &RestrictionViolationError{
+				Rule:    RestrictionStopSell,
+				Date:    key,
+				Message: fmt.Sprintf("Rooms are not on sale for the night of %s", key),
+			}
+		case date.Equal(checkIn) && restriction.ClosedToArrival:
+			return &RestrictionViolationError{
+				Rule:    RestrictionClosedToArrival,
+				Date:    key,
+				Message: fmt.Sprintf("Arrivals are not allowed on %s", key),
+			}
+		case restriction.MinStay > 0 && nights < restriction.MinStay:
+			return &RestrictionViolationError{
+				Rule:    RestrictionMinStay,
+				Date:    key,
+				Message: fmt.Sprintf("Stays including the night of %s must be at least %d night(s)", key, restriction.MinStay),
+			}
+		case restriction.MaxStay > 0 && nights > restriction.MaxStay:
+			return &RestrictionViolationError{
+				Rule:    RestrictionMaxStay,
+				Date:    key,
+				Message: fmt.Sprintf("Stays including the night of %s can be at most %d night(s)", key, restriction.MaxStay),
+			}
+		}
+	}
+	return nil
+}